@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/activitypub"
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	federationInterval = 10 * time.Second
+	federationBatch    = 100
+
+	deliveryInterval    = 10 * time.Second
+	deliveryBatch       = 50
+	maxDeliveryAttempts = 8
+	maxDeliveryBackoff  = 6 * time.Hour
+)
+
+// federateChirpEvents turns new chirp events into activities queued for the
+// remote followers of their author. The cursor row is locked for the whole
+// batch, so concurrent instances never queue an event twice.
+func (cfg *apiConfig) federateChirpEvents(ctx context.Context) error {
+	for {
+		var handled int
+		err := cfg.withTx(ctx, func(q *database.Queries) error {
+			cursor, err := q.LockActivityPubCursor(ctx)
+			if err != nil {
+				return err
+			}
+			events, err := q.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+				AfterID:   cursor,
+				MaxEvents: federationBatch,
+			})
+			if err != nil {
+				return err
+			}
+			handled = len(events)
+			if handled == 0 {
+				return nil
+			}
+
+			for _, event := range events {
+				if err := cfg.federateChirpEvent(ctx, q, event); err != nil {
+					return err
+				}
+			}
+			return q.SetActivityPubCursor(ctx, events[len(events)-1].ID)
+		})
+		if err != nil || handled < federationBatch {
+			return err
+		}
+	}
+}
+
+func (cfg *apiConfig) federateChirpEvent(ctx context.Context, q *database.Queries, event database.ChirpEvent) error {
+	author, err := q.GetUserById(ctx, event.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if author.IsPrivate {
+		return nil
+	}
+	// Deletes still go out, so that remote servers drop what they have.
+	if event.Kind == "created" {
+		restricted, err := isRestricted(ctx, q, author.ID)
+		if err != nil || restricted {
+			return err
+		}
+	}
+
+	// Rechirps are never federated; a purged chirp has nothing left to say.
+	chirp, err := q.GetChirp(ctx, event.ChirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if chirp.RechirpOfID.Valid {
+		return nil
+	}
+	// A chirp hidden since it was created isn't sent; its Delete follows.
+	if event.Kind == "created" && chirp.HiddenAt.Valid {
+		return nil
+	}
+
+	var activity activitypub.Activity
+	switch event.Kind {
+	case "created":
+		activity = cfg.createActivity(chirp)
+	case "deleted":
+		activity = cfg.deleteActivity(chirp)
+	default:
+		return nil
+	}
+
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	_, err = q.EnqueueDeliveryToFollowers(ctx, database.EnqueueDeliveryToFollowersParams{
+		UserID:  chirp.UserID,
+		Payload: string(payload),
+	})
+	return err
+}
+
+func (cfg *apiConfig) deleteActivity(chirp database.Chirp) activitypub.Activity {
+	note := cfg.newNote(chirp)
+	object, _ := json.Marshal(activitypub.Object{ID: note.ID, Type: "Tombstone"})
+	return activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      note.ID + "#delete/" + uuid.NewString(),
+		Type:    "Delete",
+		Actor:   note.AttributedTo,
+		Object:  object,
+		To:      note.To,
+		CC:      note.CC,
+	}
+}
+
+// deliverActivities sends queued activities. Failed deliveries are retried
+// with an exponential backoff, until the inbox refuses them for good or
+// they run out of attempts.
+func (cfg *apiConfig) deliverActivities(ctx context.Context) error {
+	deliveries, err := cfg.db.ClaimDueDeliveries(ctx, deliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		err := cfg.deliver(ctx, delivery)
+		if err == nil {
+			if err := cfg.db.MarkDeliveryDelivered(ctx, delivery.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var deliveryErr *activitypub.DeliveryError
+		permanent := errors.As(err, &deliveryErr) && deliveryErr.Permanent
+		if permanent || delivery.Attempts+1 >= maxDeliveryAttempts {
+			log.Printf("giving up delivery %s to %s: %v", delivery.ID, delivery.Inbox, err)
+			if err := cfg.db.FailDelivery(ctx, database.FailDeliveryParams{
+				ID:        delivery.ID,
+				LastError: err.Error(),
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := cfg.db.RetryDelivery(ctx, database.RetryDeliveryParams{
+			ID:            delivery.ID,
+			LastError:     err.Error(),
+			NextAttemptAt: time.Now().UTC().Add(backoff(time.Minute, maxDeliveryBackoff, delivery.Attempts)),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) deliver(ctx context.Context, delivery database.ActivitypubDelivery) error {
+	key, err := cfg.activityPubKey(ctx, delivery.UserID)
+	if err != nil {
+		return err
+	}
+	private, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return &activitypub.DeliveryError{Permanent: true, Err: err}
+	}
+
+	keyID := cfg.actorURL(delivery.UserID) + "#main-key"
+	return cfg.apClient.Deliver(ctx, delivery.Inbox, []byte(delivery.Payload), keyID, private)
+}
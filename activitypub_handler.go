@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/activitypub"
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/httpsig"
+	"github.com/google/uuid"
+)
+
+const (
+	outboxSize       = 20
+	maxInboxBodySize = 1 << 20
+	signatureMaxSkew = time.Hour
+	remoteActorTTL   = 24 * time.Hour
+)
+
+// Only public accounts are federated: the fediverse can't be trusted to
+// honour follow approvals.
+
+func (cfg *apiConfig) actorURL(userID uuid.UUID) string {
+	return cfg.BaseURL + "/ap/users/" + userID.String()
+}
+
+func (cfg *apiConfig) noteURL(chirpID uuid.UUID) string {
+	return cfg.BaseURL + "/ap/chirps/" + chirpID.String()
+}
+
+// federatedUser loads the user of the {userID} path value, answering 404
+// for unknown and private users.
+func (cfg *apiConfig) federatedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserById(r.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "Cannot get user", err)
+		return database.User{}, false
+	}
+	if err != nil || user.IsPrivate {
+		jsonError(w, http.StatusNotFound, "user not found", err)
+		return database.User{}, false
+	}
+
+	// Suspended and limited users aren't federated either.
+	_, err = cfg.db.GetActiveSuspension(r.Context(), userID)
+	if err == nil {
+		jsonError(w, http.StatusNotFound, "user not found", nil)
+		return database.User{}, false
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "Cannot get account status", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// activityPubKey returns the key pair of a user, creating it on first use.
+func (cfg *apiConfig) activityPubKey(ctx context.Context, userID uuid.UUID) (database.ActivitypubKey, error) {
+	key, err := cfg.db.GetActivityPubKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActivitypubKey{}, err
+	}
+	// Another request may have won the race: keep whichever was stored.
+	if err := cfg.db.CreateActivityPubKey(ctx, database.CreateActivityPubKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	}); err != nil {
+		return database.ActivitypubKey{}, err
+	}
+	return cfg.db.GetActivityPubKey(ctx, userID)
+}
+
+func (cfg *apiConfig) webfingerHandler(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		jsonError(w, http.StatusBadRequest, "no resource provided", nil)
+		return
+	}
+
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "invalid base URL", err)
+		return
+	}
+
+	// Users have no handle: they are acct:<user id>@<host>.
+	var rawID string
+	if acct, ok := strings.CutPrefix(resource, "acct:"); ok {
+		name, host, _ := strings.Cut(acct, "@")
+		if !strings.EqualFold(host, base.Host) {
+			jsonError(w, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		rawID = name
+	} else {
+		rawID, _ = strings.CutPrefix(resource, cfg.BaseURL+"/ap/users/")
+	}
+
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		jsonError(w, http.StatusNotFound, "user not found", err)
+		return
+	}
+	user, err := cfg.db.GetUserById(r.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "Cannot get user", err)
+		return
+	}
+	if err != nil || user.IsPrivate {
+		jsonError(w, http.StatusNotFound, "user not found", err)
+		return
+	}
+
+	actor := cfg.actorURL(user.ID)
+	jsonResponseAs(w, http.StatusOK, "application/jrd+json", activitypub.WebFinger{
+		Subject: fmt.Sprintf("acct:%s@%s", user.ID, base.Host),
+		Aliases: []string{actor},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actor},
+		},
+	})
+}
+
+func (cfg *apiConfig) actorHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := cfg.activityPubKey(r.Context(), user.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load key", err)
+		return
+	}
+
+	actor := cfg.actorURL(user.ID)
+	jsonResponseAs(w, http.StatusOK, activitypub.ContentType, activitypub.Actor{
+		Context:           activitypub.ActorContext(),
+		ID:                actor,
+		Type:              "Person",
+		PreferredUsername: user.ID.String(),
+		Inbox:             actor + "/inbox",
+		Outbox:            actor + "/outbox",
+		Followers:         actor + "/followers",
+		URL:               cfg.BaseURL + "/api/chirps?author_id=" + user.ID.String(),
+		PublicKey: activitypub.PublicKey{
+			ID:           actor + "#main-key",
+			Owner:        actor,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	})
+}
+
+// outboxHandler lists the latest chirps of a user as Create activities.
+func (cfg *apiConfig) outboxHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbChirps, err := cfg.db.GetChirpsByUserId(r.Context(), user.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot get chirps by author", err)
+		return
+	}
+
+	items := []any{}
+	for i := len(dbChirps) - 1; i >= 0 && len(items) < outboxSize; i-- {
+		if dbChirps[i].RechirpOfID.Valid || dbChirps[i].HiddenAt.Valid {
+			continue
+		}
+		items = append(items, cfg.createActivity(dbChirps[i]))
+	}
+
+	jsonResponseAs(w, http.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           cfg.actorURL(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   len(items),
+		OrderedItems: items,
+	})
+}
+
+// followersHandler only reveals how many remote followers a user has.
+func (cfg *apiConfig) followersHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := cfg.db.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot count followers", err)
+		return
+	}
+
+	jsonResponseAs(w, http.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: int(count),
+	})
+}
+
+func (cfg *apiConfig) noteHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), uuid.Nil)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "Cannot get chirp", err)
+		return
+	}
+	if err != nil || chirp.RechirpOfID.Valid {
+		jsonError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	note := cfg.newNote(chirp)
+	note.Context = activitypub.Context
+	jsonResponseAs(w, http.StatusOK, activitypub.ContentType, note)
+}
+
+func (cfg *apiConfig) newNote(chirp database.Chirp) activitypub.Object {
+	actor := cfg.actorURL(chirp.UserID)
+	return activitypub.Object{
+		ID:           cfg.noteURL(chirp.ID),
+		Type:         "Note",
+		AttributedTo: actor,
+		Content:      "<p>" + html.EscapeString(chirp.Body) + "</p>",
+		Summary:      chirp.ContentWarning,
+		Sensitive:    chirp.Sensitive || chirp.ContentWarning != "",
+		Published:    chirp.CreatedAt.UTC(),
+		URL:          cfg.BaseURL + "/api/chirps/" + chirp.ID.String(),
+		To:           activitypub.Audience{activitypub.Public},
+		CC:           activitypub.Audience{actor + "/followers"},
+	}
+}
+
+func (cfg *apiConfig) createActivity(chirp database.Chirp) activitypub.Activity {
+	note := cfg.newNote(chirp)
+	object, _ := json.Marshal(note)
+	return activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      note.ID + "/activity",
+		Type:    "Create",
+		Actor:   note.AttributedTo,
+		Object:  object,
+		To:      note.To,
+		CC:      note.CC,
+	}
+}
+
+// inboxHandler accepts signed activities from remote servers: follows and
+// their undoing, and notes that are created or deleted.
+func (cfg *apiConfig) inboxHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBodySize+1))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't read request body", err)
+		return
+	}
+	if len(body) > maxInboxBodySize {
+		jsonError(w, http.StatusRequestEntityTooLarge, "activity too large", nil)
+		return
+	}
+
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode activity", err)
+		return
+	}
+
+	var sender database.RemoteActor
+	_, err = httpsig.Verify(r, body, signatureMaxSkew, func(keyID string) (*rsa.PublicKey, error) {
+		sender, err = cfg.remoteActorForKey(r.Context(), keyID)
+		if err != nil {
+			return nil, err
+		}
+		return activitypub.ParsePublicKey(sender.PublicKeyPem)
+	})
+	if err != nil {
+		jsonError(w, http.StatusUnauthorized, "invalid signature", err)
+		return
+	}
+	if activity.Actor != sender.ID {
+		jsonError(w, http.StatusUnauthorized, "activity actor does not match the signature", nil)
+		return
+	}
+
+	switch activity.Type {
+	case "Follow", "Undo", "Create", "Delete":
+	default:
+		// Everything else is accepted and ignored.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	object, err := activity.ObjectRef()
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode activity object", err)
+		return
+	}
+
+	local := cfg.actorURL(user.ID)
+	switch activity.Type {
+	case "Follow":
+		if object.ID != local {
+			jsonError(w, http.StatusBadRequest, "follow is not addressed to this actor", nil)
+			return
+		}
+		if err := cfg.acceptRemoteFollow(r.Context(), user.ID, sender, activity.ID, body); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to accept follow", err)
+			return
+		}
+
+	case "Undo":
+		// Only follows can be undone here; the object is either the Follow
+		// itself or its id.
+		if object.Type == "Follow" {
+			_, err = cfg.db.DeleteRemoteFollower(r.Context(), database.DeleteRemoteFollowerParams{
+				UserID:  user.ID,
+				ActorID: sender.ID,
+			})
+		} else if object.Type == "" {
+			_, err = cfg.db.DeleteRemoteFollowerByFollowID(r.Context(), database.DeleteRemoteFollowerByFollowIDParams{
+				ActorID:          sender.ID,
+				FollowActivityID: object.ID,
+			})
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to undo follow", err)
+			return
+		}
+
+	case "Create":
+		if object.Type != "Note" {
+			break
+		}
+		if object.AttributedTo != sender.ID {
+			jsonError(w, http.StatusBadRequest, "note is not attributed to the sender", nil)
+			return
+		}
+		published := object.Published
+		if published.IsZero() {
+			published = time.Now().UTC()
+		}
+		if err := cfg.db.CreateRemoteNote(r.Context(), database.CreateRemoteNoteParams{
+			ID:          object.ID,
+			UserID:      user.ID,
+			ActorID:     sender.ID,
+			Content:     object.Content,
+			InReplyTo:   object.InReplyTo,
+			PublishedAt: published,
+		}); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to store note", err)
+			return
+		}
+
+	case "Delete":
+		// An actor deleting itself takes its follows and notes along.
+		if object.ID == sender.ID {
+			err = cfg.db.DeleteRemoteActor(r.Context(), sender.ID)
+		} else {
+			_, err = cfg.db.DeleteRemoteNote(r.Context(), database.DeleteRemoteNoteParams{
+				ID:      object.ID,
+				ActorID: sender.ID,
+			})
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to delete", err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// remoteActorForKey resolves the actor owning keyID, from the cache when it
+// is fresh enough, and keeps using a stale copy if the actor can't be
+// fetched anymore (deleted actors answer 410 to their own Delete).
+func (cfg *apiConfig) remoteActorForKey(ctx context.Context, keyID string) (database.RemoteActor, error) {
+	actorURL, _, _ := strings.Cut(keyID, "#")
+
+	cached, err := cfg.db.GetRemoteActor(ctx, actorURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return database.RemoteActor{}, err
+	}
+	usable := err == nil && cached.PublicKeyID == keyID
+	if usable && time.Since(cached.FetchedAt) < remoteActorTTL {
+		return cached, nil
+	}
+
+	actor, fetchErr := cfg.apClient.FetchActor(ctx, actorURL)
+	if fetchErr != nil {
+		if usable {
+			return cached, nil
+		}
+		return database.RemoteActor{}, fetchErr
+	}
+	if actor.PublicKey.ID != keyID {
+		return database.RemoteActor{}, fmt.Errorf("%s is not a key of %s", keyID, actorURL)
+	}
+
+	return cfg.db.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		ID:           actor.ID,
+		Inbox:        actor.Inbox,
+		PublicKeyID:  actor.PublicKey.ID,
+		PublicKeyPem: actor.PublicKey.PublicKeyPem,
+	})
+}
+
+func (cfg *apiConfig) acceptRemoteFollow(ctx context.Context, userID uuid.UUID, sender database.RemoteActor, followID string, follow []byte) error {
+	local := cfg.actorURL(userID)
+	accept, err := json.Marshal(activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      local + "#accepts/" + uuid.NewString(),
+		Type:    "Accept",
+		Actor:   local,
+		Object:  follow,
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+			UserID:           userID,
+			ActorID:          sender.ID,
+			FollowActivityID: followID,
+		}); err != nil {
+			return err
+		}
+		return q.EnqueueDelivery(ctx, database.EnqueueDeliveryParams{
+			UserID:  userID,
+			Inbox:   sender.Inbox,
+			Payload: string(accept),
+		})
+	})
+}
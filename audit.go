@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/payments"
+	"github.com/google/uuid"
+)
+
+// Audit event actions.
+const (
+	auditLoginSucceeded       = "login.succeeded"
+	auditLoginFailed          = "login.failed"
+	auditTokenRefreshed       = "token.refreshed"
+	auditTokenRevoked         = "token.revoked"
+	auditEmailChanged         = "user.email_changed"
+	auditPasswordChanged      = "user.password_changed"
+	auditRoleChanged          = "role.changed"
+	auditSubscriptionUpgraded = "subscription.upgraded"
+	auditAdminReset           = "admin.reset"
+)
+
+var auditActions = map[string]bool{
+	auditLoginSucceeded:       true,
+	auditLoginFailed:          true,
+	auditTokenRefreshed:       true,
+	auditTokenRevoked:         true,
+	auditEmailChanged:         true,
+	auditPasswordChanged:      true,
+	auditRoleChanged:          true,
+	auditSubscriptionUpgraded: true,
+	auditAdminReset:           true,
+}
+
+const (
+	defaultAuditEventsPage = 50
+	maxAuditEventsPage     = 200
+)
+
+// auditEvent is an entry to add to the audit log. The actor is who acted,
+// uuid.Nil for anonymous callers and the server itself, and the target the
+// user the action is about.
+type auditEvent struct {
+	Action   string
+	ActorID  uuid.UUID
+	TargetID uuid.UUID
+	Metadata map[string]string
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Action    string          `json:"action"`
+	ActorID   *uuid.UUID      `json:"actor_id"`
+	TargetID  *uuid.UUID      `json:"target_id"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+func newAuditEvent(e database.AuditEvent) AuditEvent {
+	event := AuditEvent{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Action:    e.Action,
+		IP:        e.Ip,
+		UserAgent: e.UserAgent,
+		Metadata:  json.RawMessage(e.Metadata),
+	}
+	if e.ActorID.Valid {
+		event.ActorID = &e.ActorID.UUID
+	}
+	if e.TargetID.Valid {
+		event.TargetID = &e.TargetID.UUID
+	}
+	return event
+}
+
+// clientIP is the address the request came from. Forwarding headers are
+// ignored since anyone can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// audit records an event caused by r, or by the server itself when r is
+// nil. Like notify, failures are logged rather than failing the request.
+func (cfg *apiConfig) audit(r *http.Request, ev auditEvent) {
+	ctx := context.Background()
+	ip, userAgent := "", ""
+	if r != nil {
+		// Still record the event when the client went away.
+		ctx = context.WithoutCancel(r.Context())
+		ip, userAgent = clientIP(r), r.UserAgent()
+	}
+
+	metadata := []byte("{}")
+	if len(ev.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(ev.Metadata); err != nil {
+			log.Printf("cannot encode %s audit event: %v", ev.Action, err)
+			return
+		}
+	}
+
+	err := cfg.db.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		Action:    ev.Action,
+		ActorID:   uuid.NullUUID{UUID: ev.ActorID, Valid: ev.ActorID != uuid.Nil},
+		TargetID:  uuid.NullUUID{UUID: ev.TargetID, Valid: ev.TargetID != uuid.Nil},
+		Ip:        ip,
+		UserAgent: userAgent,
+		Metadata:  string(metadata),
+	})
+	if err != nil {
+		log.Printf("cannot record %s audit event: %v", ev.Action, err)
+	}
+}
+
+// auditUpgrade records the upgrade a processed webhook event applied, if
+// it was one.
+func (cfg *apiConfig) auditUpgrade(r *http.Request, actorID uuid.UUID, event database.WebhookEvent) {
+	if event.Status != webhookProcessed {
+		return
+	}
+	provider, ok := cfg.paymentProviders[event.Provider]
+	if !ok {
+		return
+	}
+	ev, err := provider.DecodeEvent([]byte(event.Payload))
+	if err != nil || ev.Type != payments.SubscriptionStarted {
+		return
+	}
+
+	cfg.audit(r, auditEvent{
+		Action:   auditSubscriptionUpgraded,
+		ActorID:  actorID,
+		TargetID: ev.UserID,
+		Metadata: map[string]string{"provider": event.Provider, "webhook_event_id": event.ID},
+	})
+}
+
+// listAuditEventsHandler returns the audit log, newest first, optionally
+// filtered by action, actor_id and target_id. The next page is requested
+// with before=next_before.
+func (cfg *apiConfig) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := database.GetAuditEventsParams{Action: query.Get("action")}
+	for name, dest := range map[string]*uuid.NullUUID{
+		"actor_id":  &params.ActorID,
+		"target_id": &params.TargetID,
+	} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid "+name+" (must be UUID)", err)
+			return
+		}
+		*dest = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	cfg.respondAuditEvents(w, r, params)
+}
+
+// listSecurityEventsHandler returns the audit events about the caller,
+// newest first, such as logins to their account.
+func (cfg *apiConfig) listSecurityEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	cfg.respondAuditEvents(w, r, database.GetAuditEventsParams{
+		Action:   r.URL.Query().Get("action"),
+		TargetID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+}
+
+// respondAuditEvents answers a page of the audit events matching params,
+// read from the limit and before query parameters.
+func (cfg *apiConfig) respondAuditEvents(w http.ResponseWriter, r *http.Request, params database.GetAuditEventsParams) {
+	type response struct {
+		Events     []AuditEvent `json:"events"`
+		NextBefore *uuid.UUID   `json:"next_before"`
+	}
+
+	if params.Action != "" && !auditActions[params.Action] {
+		jsonError(w, http.StatusBadRequest, "unknown action", nil)
+		return
+	}
+
+	limit := defaultAuditEventsPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxAuditEventsPage {
+			jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditEventsPage), err)
+			return
+		}
+		limit = n
+	}
+	params.MaxResults = int32(limit)
+
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid before (must be UUID)", err)
+			return
+		}
+		params.Before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbEvents, err := cfg.db.GetAuditEvents(r.Context(), params)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list audit events", err)
+		return
+	}
+
+	resp := response{Events: make([]AuditEvent, 0, len(dbEvents))}
+	for _, e := range dbEvents {
+		resp.Events = append(resp.Events, newAuditEvent(e))
+	}
+	if len(dbEvents) == limit {
+		last := dbEvents[len(dbEvents)-1].ID
+		resp.NextBefore = &last
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
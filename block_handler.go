@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type UserRelation struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// relationTarget authenticates the caller and resolves the {userID} path
+// value shared by the block and mute endpoints.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	targetID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if targetID == userID {
+		jsonError(w, http.StatusBadRequest, "cannot target yourself", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if _, err := cfg.db.GetUserById(r.Context(), targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return uuid.Nil, uuid.Nil, false
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load user", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, targetID, true
+}
+
+func (cfg *apiConfig) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to block user", err)
+		return
+	}
+
+	// Blocking severs any follow relationship in both directions.
+	if err := cfg.db.RemoveRelationshipsBetween(r.Context(), database.RemoveRelationshipsBetweenParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to remove follows", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to unblock user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to mute user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to unmute user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	relations, ok := cfg.loadBlocks(w, r)
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, relations)
+}
+
+func (cfg *apiConfig) exportBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	relations, ok := cfg.loadBlocks(w, r)
+	if !ok {
+		return
+	}
+	writeRelationsCSV(w, "blocks.csv", relations)
+}
+
+func (cfg *apiConfig) listMutesHandler(w http.ResponseWriter, r *http.Request) {
+	relations, ok := cfg.loadMutes(w, r)
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, relations)
+}
+
+func (cfg *apiConfig) exportMutesHandler(w http.ResponseWriter, r *http.Request) {
+	relations, ok := cfg.loadMutes(w, r)
+	if !ok {
+		return
+	}
+	writeRelationsCSV(w, "mutes.csv", relations)
+}
+
+func (cfg *apiConfig) loadBlocks(w http.ResponseWriter, r *http.Request) ([]UserRelation, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	blocks, err := cfg.db.GetBlocksByUser(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list blocked users", err)
+		return nil, false
+	}
+
+	relations := make([]UserRelation, 0, len(blocks))
+	for _, b := range blocks {
+		relations = append(relations, UserRelation{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	return relations, true
+}
+
+func (cfg *apiConfig) loadMutes(w http.ResponseWriter, r *http.Request) ([]UserRelation, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	mutes, err := cfg.db.GetMutesByUser(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list muted users", err)
+		return nil, false
+	}
+
+	relations := make([]UserRelation, 0, len(mutes))
+	for _, m := range mutes {
+		relations = append(relations, UserRelation{UserID: m.MutedID, CreatedAt: m.CreatedAt})
+	}
+	return relations, true
+}
+
+func writeRelationsCSV(w http.ResponseWriter, filename string, relations []UserRelation) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"user_id", "created_at"})
+	for _, rel := range relations {
+		cw.Write([]string{rel.UserID.String(), rel.CreatedAt.UTC().Format(time.RFC3339)})
+	}
+	cw.Flush()
+}
+
+// blockedBetween reports whether either user has blocked the other.
+func (cfg *apiConfig) blockedBetween(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	blocked, err := cfg.db.IsBlocked(ctx, database.IsBlockedParams{BlockerID: a, BlockedID: b})
+	if err != nil || blocked {
+		return blocked, err
+	}
+	return cfg.db.IsBlocked(ctx, database.IsBlockedParams{BlockerID: b, BlockedID: a})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) bookmarkChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return
+	}
+
+	if err := cfg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to bookmark chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unbookmarkChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to remove bookmark", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbChirps, err := cfg.db.GetBookmarkedChirps(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list bookmarks", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	// A bookmarked chirp can become hidden later (e.g. its author went
+	// private), so bookmarks are filtered on every read.
+	dbChirps, err = cfg.filterVisible(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot filter chirps", err)
+		return
+	}
+
+	chirps, err := cfg.hydrateChirps(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirps", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, chirps)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -21,11 +22,70 @@ type Chirp struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	UserID      uuid.UUID `json:"user_id"`
 	CleanedBody string    `json:"body"`
+
+	// Rechirps carry no body of their own and embed the original; quotes
+	// embed the quoted chirp. A quoted chirp that was deleted or is hidden
+	// from the reader is reported through QuoteUnavailable.
+	RechirpOfID      *uuid.UUID `json:"rechirp_of_id,omitempty"`
+	RechirpOf        *Chirp     `json:"rechirp_of,omitempty"`
+	QuoteOfID        *uuid.UUID `json:"quote_of_id,omitempty"`
+	QuoteOf          *Chirp     `json:"quote_of,omitempty"`
+	QuoteUnavailable bool       `json:"quote_unavailable,omitempty"`
+	RechirpCount     int64      `json:"rechirp_count"`
+
+	// Only set while the chirp is scheduled and visible to its author alone.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+
+	// Only set once a moderator hid the chirp from everyone else.
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+
+	Poll *Poll `json:"poll,omitempty"`
+
+	// Collapsed tells clients to hide the body behind the content warning,
+	// according to the reader's collapse_sensitive setting.
+	ContentWarning string `json:"content_warning"`
+	Sensitive      bool   `json:"sensitive"`
+	Collapsed      bool   `json:"collapsed"`
+
+	// Only ever true in author-filtered listings.
+	Pinned bool `json:"pinned"`
+}
+
+func newChirp(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:          dbChirp.ID,
+		CreatedAt:   dbChirp.CreatedAt,
+		UpdatedAt:   dbChirp.UpdatedAt,
+		UserID:      dbChirp.UserID,
+		CleanedBody: dbChirp.Body,
+
+		ContentWarning: dbChirp.ContentWarning,
+		Sensitive:      dbChirp.Sensitive,
+	}
+	if dbChirp.RechirpOfID.Valid {
+		chirp.RechirpOfID = &dbChirp.RechirpOfID.UUID
+	}
+	if dbChirp.QuoteOfID.Valid {
+		chirp.QuoteOfID = &dbChirp.QuoteOfID.UUID
+	}
+	if dbChirp.PublishAt.Valid {
+		chirp.PublishAt = &dbChirp.PublishAt.Time
+	}
+	if dbChirp.HiddenAt.Valid {
+		chirp.HiddenAt = &dbChirp.HiddenAt.Time
+	}
+	return chirp
 }
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string          `json:"body"`
+		QuoteOfID *uuid.UUID      `json:"quote_of_id"`
+		PublishAt *time.Time      `json:"publish_at"`
+		Poll      *pollParameters `json:"poll"`
+
+		ContentWarning string `json:"content_warning"`
+		Sensitive      bool   `json:"sensitive"`
 	}
 
 	// 1) Auth: Bearer + JWT
@@ -40,6 +100,9 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
 		return
 	}
+	if !cfg.checkUserExists(w, r, userID) || !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
 
 	// 2) Decode body
 	var params parameters
@@ -48,44 +111,114 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ent, err := cfg.userEntitlements(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Couldn't load user", err)
+		return
+	}
+	if !cfg.checkChirpRate(w, r, userID, ent) {
+		return
+	}
+
 	// 3) Validation + nettoyage
-	const maxChirpLength = 140
-	if len(params.Body) > maxChirpLength {
-		jsonError(w, http.StatusBadRequest, "chirp is too long", nil)
+	cleaned, err := cleanBody(params.Body, ent.MaxChirpLength)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
-	words := strings.Split(params.Body, " ")
-	cleaned := strings.Join(validateWords(words), " ")
+	contentWarning, err := cleanContentWarning(params.ContentWarning)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	publishAt, err := parsePublishAt(params.PublishAt)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
 
-	// 4) Création en DB avec l'user issu du JWT
+	// Les sondages sont réservés à Chirpy Red
+	if params.Poll != nil {
+		opensAt := time.Now()
+		if publishAt.Valid {
+			opensAt = publishAt.Time
+		}
+		if err := params.Poll.validate(opensAt); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if !ent.Polls {
+			jsonError(w, http.StatusForbidden, "polls require Chirpy Red", nil)
+			return
+		}
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	// 4) Citation éventuelle d'un autre chirp
+	quoteOfID := uuid.NullUUID{}
+	var quotedAuthorID uuid.UUID
+	if params.QuoteOfID != nil {
+		quoted, ok := cfg.shareableChirp(w, r, viewer, *params.QuoteOfID)
+		if !ok {
+			return
+		}
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+		quotedAuthorID = quoted.UserID
+	}
+
+	// 5) Création en DB avec l'user issu du JWT
 	createParams := database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
+		Body:      cleaned,
+		UserID:    userID,
+		QuoteOfID: quoteOfID,
+		PublishAt: publishAt,
+
+		ContentWarning: contentWarning,
+		Sensitive:      params.Sensitive,
 	}
-	chirp, err := cfg.db.CreateChirp(r.Context(), createParams)
+	var chirp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chirp, err = q.CreateChirp(r.Context(), createParams)
+		if err != nil || params.Poll == nil {
+			return err
+		}
+		return createPoll(r.Context(), q, chirp.ID, *params.Poll)
+	})
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, fmt.Sprintf("cannot create chirp: %v", err), err)
 		return
 	}
 
-	// 5) Réponse
-	jsonResponse(w, http.StatusCreated, Chirp{
-		ID:          chirp.ID,
-		CreatedAt:   chirp.CreatedAt,
-		UpdatedAt:   chirp.UpdatedAt,
-		UserID:      chirp.UserID,
-		CleanedBody: cleaned,
-	})
+	// Les citations programmées notifient à leur publication
+	if quoteOfID.Valid && !chirp.PublishAt.Valid {
+		cfg.notify(r.Context(), quotedAuthorID, notificationQuote, userID, chirp.ID)
+	}
+
+	// 6) Réponse
+	cfg.respondWithChirp(w, r, http.StatusCreated, viewer, chirp)
 }
 
 func (cfg *apiConfig) GetChirps(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := cfg.optionalUser(w, r)
+	if !ok {
+		return
+	}
+
 	authorID := r.URL.Query().Get("author_id")
 	sortMethod := r.URL.Query().Get("sort")
 
 	var (
 		dbChirps []database.Chirp
 		err      error
+		pinnedID uuid.UUID
 	)
 
 	if authorID != "" {
@@ -95,6 +228,15 @@ func (cfg *apiConfig) GetChirps(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		author, authorErr := cfg.db.GetUserById(r.Context(), uid)
+		if authorErr != nil && !errors.Is(authorErr, sql.ErrNoRows) {
+			jsonError(w, http.StatusInternalServerError, "Cannot get author", authorErr)
+			return
+		}
+		if author.PinnedChirpID.Valid {
+			pinnedID = author.PinnedChirpID.UUID
+		}
+
 		dbChirps, err = cfg.db.GetChirpsByUserId(r.Context(), uid)
 		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "Cannot get chirps by author", err)
@@ -108,6 +250,17 @@ func (cfg *apiConfig) GetChirps(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	viewer, err := cfg.loadChirpViewer(r.Context(), viewerID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+	dbChirps, err = cfg.filterListing(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot filter chirps", err)
+		return
+	}
+
 	switch sortMethod {
 		case "desc":
 			sort.Slice(dbChirps, func(i, j int) bool {
@@ -120,21 +273,26 @@ func (cfg *apiConfig) GetChirps(w http.ResponseWriter, r *http.Request) {
 	}
 
 
-	chirps := make([]Chirp, 0, len(dbChirps))
-	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:          dbChirp.ID,
-			CreatedAt:   dbChirp.CreatedAt,
-			UpdatedAt:   dbChirp.UpdatedAt,
-			UserID:      dbChirp.UserID,
-			CleanedBody: dbChirp.Body,
-		})
+	chirps, err := cfg.hydrateChirps(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirps", err)
+		return
+	}
+
+	chirps = collapseRechirps(chirps)
+	if pinnedID != uuid.Nil {
+		chirps = movePinnedFirst(chirps, pinnedID)
 	}
 
 	jsonResponse(w, http.StatusOK, chirps)
 }
 
 func (cfg *apiConfig) GetChirp(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := cfg.optionalUser(w, r)
+	if !ok {
+		return
+	}
+
 	chirpIDStr := r.PathValue("chirpID")
 	if chirpIDStr == "" {
 		jsonError(w, http.StatusBadRequest, "No Chirp ID provided", nil)
@@ -146,21 +304,23 @@ func (cfg *apiConfig) GetChirp(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusBadRequest, "invalid chirp ID (must be UUID)", err)
 		return
 	}
-	rawChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	viewer, err := cfg.loadChirpViewer(r.Context(), viewerID)
 	if err != nil {
-		jsonError(w, http.StatusNotFound, fmt.Sprintf("No Chirp found for id %s", chirpID), err)
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
 		return
 	}
 
-	chirp := Chirp{
-		ID:          rawChirp.ID,
-		CreatedAt:   rawChirp.CreatedAt,
-		UpdatedAt:   rawChirp.UpdatedAt,
-		UserID:      rawChirp.UserID,
-		CleanedBody: rawChirp.Body,
+	rawChirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, fmt.Sprintf("No Chirp found for id %s", chirpID), err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirp", err)
+		return
 	}
 
-	jsonResponse(w, http.StatusOK, chirp)
+	cfg.respondWithChirp(w, r, http.StatusOK, viewer, rawChirp)
 }
 
 func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -174,6 +334,9 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
 		return
 	}
+	if !cfg.checkUserExists(w, r, userID) || !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
 
 	chirpIDStr := r.PathValue("chirpID")
 	if chirpIDStr == "" {
@@ -196,12 +359,31 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if chirp.DeletedAt.Valid {
+		jsonError(w, http.StatusNotFound, "chirp not found", nil)
+		return
+	}
+
 	if chirp.UserID != userID {
 		jsonError(w, http.StatusForbidden, "not the author of this chirp", nil)
 		return
 	}
 
-	if err := cfg.db.DeleteChirp(r.Context(), chirpID); err != nil {
+	// Un rechirp n'a rien à restaurer : il est supprimé directement
+	if chirp.RechirpOfID.Valid {
+		if _, err := cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+			UserID:      userID,
+			RechirpOfID: chirp.RechirpOfID,
+		}); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to delete chirp", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Suppression douce : restaurable pendant cfg.ChirpRestoreWindow
+	if err := cfg.db.SoftDeleteChirp(r.Context(), chirpID); err != nil {
 		jsonError(w, http.StatusInternalServerError, "failed to delete chirp", err)
 		return
 	}
@@ -209,6 +391,90 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (cfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return
+	}
+	if err != nil || chirp.UserID != userID {
+		jsonError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	if !chirp.DeletedAt.Valid {
+		jsonError(w, http.StatusConflict, "chirp is not deleted", nil)
+		return
+	}
+
+	restored, err := cfg.db.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:            chirpID,
+		WindowSeconds: int64(cfg.ChirpRestoreWindow.Seconds()),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to restore chirp", err)
+		return
+	}
+	if restored == 0 {
+		jsonError(w, http.StatusGone, "the restore window for this chirp has passed", nil)
+		return
+	}
+
+	chirp, err = cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	cfg.respondWithChirp(w, r, http.StatusOK, viewer, chirp)
+}
+
+const maxChirpLength = 140
+
+var errChirpTooLong = errors.New("chirp is too long")
+
+// cleanBody is the content filter shared by everything users post: it
+// enforces the length limit and masks blacklisted words.
+func cleanBody(body string, maxLength int) (string, error) {
+	if len(body) > maxLength {
+		return "", errChirpTooLong
+	}
+
+	words := strings.Split(body, " ")
+	return strings.Join(validateWords(words), " "), nil
+}
+
+const maxContentWarningLength = 100
+
+// cleanContentWarning applies the same word filter as chirp bodies to a
+// content warning.
+func cleanContentWarning(cw string) (string, error) {
+	cw = strings.TrimSpace(cw)
+	if len(cw) > maxContentWarningLength {
+		return "", errors.New("content warning is too long")
+	}
+	if cw == "" {
+		return "", nil
+	}
+	return strings.Join(validateWords(strings.Split(cw, " ")), " "), nil
+}
+
 func validateWords(words []string) []string {
 
 	blackList := map[string]bool{
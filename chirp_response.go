@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// hydrateChirps turns database chirps into API chirps, embedding rechirped
+// and quoted chirps the viewer is allowed to see, rechirp counts and polls.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer *chirpViewer, dbChirps []database.Chirp) ([]Chirp, error) {
+	refIDs := []uuid.UUID{}
+	for _, c := range dbChirps {
+		if c.RechirpOfID.Valid {
+			refIDs = append(refIDs, c.RechirpOfID.UUID)
+		}
+		if c.QuoteOfID.Valid {
+			refIDs = append(refIDs, c.QuoteOfID.UUID)
+		}
+	}
+
+	refs := map[uuid.UUID]database.Chirp{}
+	if len(refIDs) > 0 {
+		dbRefs, err := cfg.db.GetChirpsByIDs(ctx, refIDs)
+		if err != nil {
+			return nil, err
+		}
+		dbRefs, err = cfg.filterVisible(ctx, viewer, dbRefs)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range dbRefs {
+			refs[ref.ID] = ref
+		}
+	}
+
+	countIDs := make([]uuid.UUID, 0, len(dbChirps)+len(refs))
+	for _, c := range dbChirps {
+		countIDs = append(countIDs, c.ID)
+	}
+	for id := range refs {
+		countIDs = append(countIDs, id)
+	}
+	counts := map[uuid.UUID]int64{}
+	rows, err := cfg.db.GetRechirpCounts(ctx, countIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.RechirpOfID.UUID] = row.Count
+	}
+
+	polls, err := cfg.loadPolls(ctx, viewer, countIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	build := func(c database.Chirp) Chirp {
+		chirp := newChirp(c)
+		chirp.RechirpCount = counts[c.ID]
+		chirp.Poll = polls[c.ID]
+		chirp.Collapsed = viewer.collapseSensitive && (c.Sensitive || c.ContentWarning != "")
+		return chirp
+	}
+	embed := func(id uuid.UUID) *Chirp {
+		ref, ok := refs[id]
+		if !ok {
+			return nil
+		}
+		chirp := build(ref)
+		return &chirp
+	}
+
+	chirps := make([]Chirp, 0, len(dbChirps))
+	for _, c := range dbChirps {
+		chirp := build(c)
+		if c.RechirpOfID.Valid {
+			chirp.RechirpOf = embed(c.RechirpOfID.UUID)
+		}
+		if c.QuoteOfID.Valid {
+			chirp.QuoteOf = embed(c.QuoteOfID.UUID)
+			chirp.QuoteUnavailable = chirp.QuoteOf == nil
+		}
+		chirps = append(chirps, chirp)
+	}
+
+	return chirps, nil
+}
+
+// collapseRechirps keeps a single entry per original chirp in a timeline:
+// the first of the original itself or any of its rechirps wins. Rechirps
+// whose original can't be shown are dropped.
+func collapseRechirps(chirps []Chirp) []Chirp {
+	seen := map[uuid.UUID]bool{}
+	out := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		originalID := c.ID
+		if c.RechirpOfID != nil {
+			if c.RechirpOf == nil {
+				continue
+			}
+			originalID = *c.RechirpOfID
+		}
+		if seen[originalID] {
+			continue
+		}
+		seen[originalID] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+func (cfg *apiConfig) respondWithChirp(w http.ResponseWriter, r *http.Request, code int, viewer *chirpViewer, dbChirp database.Chirp) {
+	chirps, err := cfg.hydrateChirps(r.Context(), viewer, []database.Chirp{dbChirp})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirp", err)
+		return
+	}
+	jsonResponse(w, code, chirps[0])
+}
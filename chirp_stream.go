@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	chirpEventsChannel  = "chirp_events"
+	chirpEventRetention = 24 * time.Hour
+
+	streamBacklogLimit = 500
+	streamBufferSize   = 64
+	streamHeartbeat    = 15 * time.Second
+	// How often the hub looks again for events held back by an older
+	// transaction.
+	streamRetryDelay = time.Second
+)
+
+var (
+	hashtagPattern = regexp.MustCompile(`#(\w+)`)
+	validHashtag   = regexp.MustCompile(`^\w{1,100}$`)
+)
+
+// chirpHub fans chirp events out to the SSE streams open on this instance.
+// Every instance runs its own hub, so a chirp created through one of them
+// reaches the clients of all of them. Events are read from the chirp_events
+// table in the order GetChirpEventsAfter returns them, so that a client
+// resuming from its Last-Event-ID never misses one; the notifications sent
+// by Postgres only tell the hub when to look.
+type chirpHub struct {
+	db *database.Queries
+
+	mu          sync.Mutex
+	subscribers map[chan database.ChirpEvent]struct{}
+	// The last event broadcast.
+	lastID int64
+}
+
+func newChirpHub(db *database.Queries) *chirpHub {
+	return &chirpHub{
+		db:          db,
+		subscribers: map[chan database.ChirpEvent]struct{}{},
+	}
+}
+
+func (h *chirpHub) subscribe() chan database.ChirpEvent {
+	ch := make(chan database.ChirpEvent, streamBufferSize)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *chirpHub) unsubscribe(ch chan database.ChirpEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// broadcast never blocks: a subscriber whose buffer is full is dropped, and
+// its client resumes from its Last-Event-ID when it reconnects.
+func (h *chirpHub) broadcast(event database.ChirpEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID = event.ID
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// run broadcasts chirp events until ctx is done. The listener reconnects on
+// its own, and the table is read again every minute in case a notification
+// was missed while it was disconnected.
+func (h *chirpHub) run(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("chirp events listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(chirpEventsChannel); err != nil {
+		log.Printf("cannot listen to %s: %v", chirpEventsChannel, err)
+		return
+	}
+
+	lastID, err := h.db.GetLatestChirpEventID(ctx)
+	if err != nil {
+		log.Printf("cannot load latest chirp event: %v", err)
+	}
+	h.mu.Lock()
+	h.lastID = lastID
+	h.mu.Unlock()
+
+	// Set while committed events wait for an older transaction to end,
+	// which doesn't necessarily send a notification when it does.
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		// A nil notification means the connection was re-established.
+		case <-listener.Notify:
+		case <-retry:
+		case <-time.After(time.Minute):
+			go listener.Ping()
+		}
+
+		retry = nil
+		held, err := h.catchUp(ctx)
+		if err != nil {
+			log.Printf("cannot catch up on chirp events: %v", err)
+			continue
+		}
+		if held {
+			retry = time.After(streamRetryDelay)
+		}
+	}
+}
+
+// catchUp broadcasts the events that follow the last one broadcast, and
+// reports whether more are held back.
+func (h *chirpHub) catchUp(ctx context.Context) (bool, error) {
+	for {
+		h.mu.Lock()
+		after := h.lastID
+		h.mu.Unlock()
+
+		events, err := h.db.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+			AfterID:   after,
+			MaxEvents: streamBacklogLimit,
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, event := range events {
+			h.broadcast(event)
+		}
+		if len(events) < streamBacklogLimit {
+			return h.db.HasHeldChirpEvents(ctx)
+		}
+	}
+}
+
+// hasHashtag reports whether body contains #tag, ignoring case.
+func hasHashtag(body, tag string) bool {
+	for _, m := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		if strings.EqualFold(m[1], tag) {
+			return true
+		}
+	}
+	return false
+}
+
+type chirpStream struct {
+	cfg      *apiConfig
+	viewer   *chirpViewer
+	authorID uuid.UUID
+	hashtag  string
+}
+
+func (cfg *apiConfig) streamChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := cfg.optionalUser(w, r)
+	if !ok {
+		return
+	}
+
+	stream := &chirpStream{cfg: cfg}
+
+	if raw := r.URL.Query().Get("author_id"); raw != "" {
+		authorID, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid author_id (must be UUID)", err)
+			return
+		}
+		stream.authorID = authorID
+	}
+
+	if raw := r.URL.Query().Get("hashtag"); raw != "" {
+		tag := strings.TrimPrefix(raw, "#")
+		if !validHashtag.MatchString(tag) {
+			jsonError(w, http.StatusBadRequest, "invalid hashtag", nil)
+			return
+		}
+		stream.hashtag = tag
+	}
+
+	// EventSource sends Last-Event-ID when it reconnects; the query
+	// parameter lets a fresh page resume where a previous one stopped.
+	var lastEventID int64
+	rawLastID := r.Header.Get("Last-Event-ID")
+	if rawLastID == "" {
+		rawLastID = r.URL.Query().Get("last_event_id")
+	}
+	if rawLastID != "" {
+		id, err := strconv.ParseInt(rawLastID, 10, 64)
+		if err != nil || id < 0 {
+			jsonError(w, http.StatusBadRequest, "invalid Last-Event-ID", err)
+			return
+		}
+		lastEventID = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, http.StatusInternalServerError, "streaming is not supported", nil)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), viewerID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+	stream.viewer = viewer
+
+	// Subscribe before reading the backlog so that nothing falls in between.
+	events := cfg.chirpHub.subscribe()
+	defer cfg.chirpHub.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	replayed := map[int64]bool{}
+	if lastEventID > 0 {
+		backlog, err := cfg.db.GetChirpEventsAfter(r.Context(), database.GetChirpEventsAfterParams{
+			AfterID:   lastEventID,
+			MaxEvents: streamBacklogLimit,
+		})
+		if err != nil {
+			log.Printf("cannot load chirp events backlog: %v", err)
+			return
+		}
+		for _, event := range backlog {
+			replayed[event.ID] = true
+			if err := stream.send(r.Context(), w, event); err != nil {
+				log.Printf("chirp stream: %v", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind: the client reconnects and
+				// resumes from its Last-Event-ID.
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := stream.send(r.Context(), w, event); err != nil {
+				log.Printf("chirp stream: %v", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// send writes event to the stream if it matches the filters and the viewer
+// is allowed to see it. Creations carry the chirp as GET /api/chirps/{id}
+// would return it, deletions only its id.
+func (s *chirpStream) send(ctx context.Context, w http.ResponseWriter, event database.ChirpEvent) error {
+	if s.authorID != uuid.Nil && event.UserID != s.authorID {
+		return nil
+	}
+	if s.viewer.muted[event.UserID] {
+		return nil
+	}
+
+	var payload any
+	switch event.Kind {
+	case "created":
+		chirp, err := s.cfg.getVisibleChirp(ctx, s.viewer, event.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s.hashtag != "" && !hasHashtag(chirp.Body, s.hashtag) {
+			return nil
+		}
+		chirps, err := s.cfg.hydrateChirps(ctx, s.viewer, []database.Chirp{chirp})
+		if err != nil {
+			return err
+		}
+		payload = chirps[0]
+
+	case "deleted":
+		if err := s.cfg.loadAuthors(ctx, s.viewer, []database.Chirp{{UserID: event.UserID}}); err != nil {
+			return err
+		}
+		if !s.viewer.canSeeAuthor(event.UserID) {
+			return nil
+		}
+		// Soft-deleted chirps are still around to be matched; purged ones
+		// are reported to every hashtag stream.
+		if s.hashtag != "" {
+			chirp, err := s.cfg.db.GetChirp(ctx, event.ChirpID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err == nil && !hasHashtag(chirp.Body, s.hashtag) {
+				return nil
+			}
+		}
+		payload = struct {
+			ID uuid.UUID `json:"id"`
+		}{ID: event.ChirpID}
+
+	default:
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: chirp.%s\ndata: %s\n\n", event.ID, event.Kind, data)
+	return err
+}
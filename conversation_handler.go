@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxConversationParticipants caps group conversations, creator included.
+const maxConversationParticipants = 10
+
+type Conversation struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	UnreadCount    int64       `json:"unread_count"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (cfg *apiConfig) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	// Dedupe and always include the creator.
+	participants := []uuid.UUID{userID}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.ParticipantIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		participants = append(participants, id)
+	}
+
+	if len(participants) < 2 {
+		jsonError(w, http.StatusBadRequest, "a conversation needs at least one other participant", nil)
+		return
+	}
+	if len(participants) > maxConversationParticipants {
+		jsonError(w, http.StatusBadRequest, "too many participants", nil)
+		return
+	}
+
+	users, err := cfg.db.GetUsersByIDs(r.Context(), participants)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load participants", err)
+		return
+	}
+	if len(users) != len(participants) {
+		jsonError(w, http.StatusNotFound, "user not found", nil)
+		return
+	}
+
+	blocked, err := cfg.blockedWithAny(r.Context(), userID, participants)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to check blocks", err)
+		return
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you cannot message one of these users", nil)
+		return
+	}
+
+	// One-to-one conversations are reused rather than duplicated.
+	if len(participants) == 2 {
+		existing, err := cfg.db.GetDirectConversation(r.Context(), database.GetDirectConversationParams{
+			UserID:   participants[0],
+			UserID_2: participants[1],
+		})
+		if err == nil {
+			cfg.respondWithConversation(w, r, http.StatusOK, existing, userID)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusInternalServerError, "failed to look up conversation", err)
+			return
+		}
+	}
+
+	// A conversation without its participants would be visible to nobody.
+	var conversation database.Conversation
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		conversation, err = q.CreateConversation(r.Context())
+		if err != nil {
+			return err
+		}
+		return q.AddConversationParticipants(r.Context(), database.AddConversationParticipantsParams{
+			ConversationID: conversation.ID,
+			UserIds:        participants,
+		})
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to create conversation", err)
+		return
+	}
+
+	cfg.respondWithConversation(w, r, http.StatusCreated, conversation, userID)
+}
+
+func (cfg *apiConfig) listConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.db.GetConversationsForUser(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list conversations", err)
+		return
+	}
+
+	conversations := make([]Conversation, 0, len(rows))
+	for _, row := range rows {
+		participantIDs, err := cfg.db.GetConversationParticipantIDs(r.Context(), row.ID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load participants", err)
+			return
+		}
+		conversations = append(conversations, Conversation{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			ParticipantIDs: participantIDs,
+			UnreadCount:    row.UnreadCount,
+		})
+	}
+
+	jsonResponse(w, http.StatusOK, conversations)
+}
+
+func (cfg *apiConfig) unreadMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := cfg.db.GetUnreadMessageCount(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to count unread messages", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, response{UnreadCount: count})
+}
+
+func (cfg *apiConfig) sendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	cleaned, err := cleanBody(params.Body, maxChirpLength)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	participantIDs, err := cfg.db.GetConversationParticipantIDs(r.Context(), conversationID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load participants", err)
+		return
+	}
+	blocked, err := cfg.blockedWithAny(r.Context(), userID, participantIDs)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to check blocks", err)
+		return
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you cannot message one of these users", nil)
+		return
+	}
+
+	message, err := cfg.db.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           cleaned,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to send message", err)
+		return
+	}
+
+	if err := cfg.db.TouchConversation(r.Context(), conversationID); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to update conversation", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newMessage(message))
+}
+
+func (cfg *apiConfig) listMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	_, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	dbMessages, err := cfg.db.GetMessages(r.Context(), conversationID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list messages", err)
+		return
+	}
+
+	messages := make([]Message, 0, len(dbMessages))
+	for _, m := range dbMessages {
+		messages = append(messages, newMessage(m))
+	}
+
+	jsonResponse(w, http.StatusOK, messages)
+}
+
+func (cfg *apiConfig) markConversationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, conversationID, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to mark conversation read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// conversationMember authenticates the caller and checks they take part in
+// the {conversationID} conversation. Outsiders get a 404 so conversation
+// IDs can't be probed.
+func (cfg *apiConfig) conversationMember(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	conversationID, ok := parseUUIDPathValue(w, r, "conversationID")
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	member, err := cfg.db.IsConversationParticipant(r.Context(), database.IsConversationParticipantParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load conversation", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !member {
+		jsonError(w, http.StatusNotFound, "conversation not found", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, conversationID, true
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, r *http.Request, code int, conversation database.Conversation, userID uuid.UUID) {
+	participantIDs, err := cfg.db.GetConversationParticipantIDs(r.Context(), conversation.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load participants", err)
+		return
+	}
+
+	jsonResponse(w, code, Conversation{
+		ID:             conversation.ID,
+		CreatedAt:      conversation.CreatedAt,
+		UpdatedAt:      conversation.UpdatedAt,
+		ParticipantIDs: participantIDs,
+	})
+}
+
+// blockedWithAny reports whether a block exists between userID and any of
+// the other participants.
+func (cfg *apiConfig) blockedWithAny(ctx context.Context, userID uuid.UUID, participantIDs []uuid.UUID) (bool, error) {
+	for _, id := range participantIDs {
+		if id == userID {
+			continue
+		}
+		blocked, err := cfg.blockedBetween(ctx, userID, id)
+		if err != nil || blocked {
+			return blocked, err
+		}
+	}
+	return false, nil
+}
+
+func newMessage(m database.Message) Message {
+	return Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+	}
+}
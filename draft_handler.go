@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Drafts are stored as typed and only go through cleanBody when published.
+const maxDraftLength = 10 * maxChirpLength
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+type draftParameters struct {
+	Body string `json:"body"`
+}
+
+func (cfg *apiConfig) listDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbDrafts, err := cfg.db.GetDraftsByUserId(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list drafts", err)
+		return
+	}
+
+	drafts := make([]Draft, 0, len(dbDrafts))
+	for _, d := range dbDrafts {
+		drafts = append(drafts, newDraft(d))
+	}
+
+	jsonResponse(w, http.StatusOK, drafts)
+}
+
+func (cfg *apiConfig) createDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	var params draftParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if len(params.Body) > maxDraftLength {
+		jsonError(w, http.StatusBadRequest, "draft is too long", nil)
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID: userID,
+		Body:   params.Body,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to create draft", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newDraft(draft))
+}
+
+func (cfg *apiConfig) updateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	_, draft, ok := cfg.ownedDraft(w, r)
+	if !ok {
+		return
+	}
+
+	var params draftParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if len(params.Body) > maxDraftLength {
+		jsonError(w, http.StatusBadRequest, "draft is too long", nil)
+		return
+	}
+
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:   draft.ID,
+		Body: params.Body,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to update draft", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newDraft(draft))
+}
+
+func (cfg *apiConfig) deleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	_, draft, ok := cfg.ownedDraft(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteDraft(r.Context(), draft.ID); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to delete draft", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishDraftHandler turns a draft into a chirp, immediately or at the
+// optional publish_at, and removes the draft.
+func (cfg *apiConfig) publishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	userID, draft, ok := cfg.ownedDraft(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+			return
+		}
+	}
+
+	publishAt, err := parsePublishAt(params.PublishAt)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	ent, err := cfg.userEntitlements(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Couldn't load user", err)
+		return
+	}
+	if !cfg.checkChirpRate(w, r, userID, ent) {
+		return
+	}
+
+	cleaned, err := cleanBody(draft.Body, ent.MaxChirpLength)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// The draft goes away with the chirp it became, so a failure can't
+	// leave both behind.
+	var chirp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chirp, err = q.CreateChirp(r.Context(), database.CreateChirpParams{
+			Body:      cleaned,
+			UserID:    userID,
+			PublishAt: publishAt,
+		})
+		if err != nil {
+			return err
+		}
+		return q.DeleteDraft(r.Context(), draft.ID)
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "cannot publish draft", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	cfg.respondWithChirp(w, r, http.StatusCreated, viewer, chirp)
+}
+
+func (cfg *apiConfig) listScheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbChirps, err := cfg.db.GetScheduledChirpsByUserId(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list scheduled chirps", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	chirps, err := cfg.hydrateChirps(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirps", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, chirps)
+}
+
+// ownedDraft loads the {draftID} draft of the authenticated user. Other
+// users' drafts answer 404.
+func (cfg *apiConfig) ownedDraft(w http.ResponseWriter, r *http.Request) (uuid.UUID, database.Draft, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return uuid.Nil, database.Draft{}, false
+	}
+
+	draftID, ok := parseUUIDPathValue(w, r, "draftID")
+	if !ok {
+		return uuid.Nil, database.Draft{}, false
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), draftID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "failed to load draft", err)
+		return uuid.Nil, database.Draft{}, false
+	}
+	if err != nil || draft.UserID != userID {
+		jsonError(w, http.StatusNotFound, "draft not found", err)
+		return uuid.Nil, database.Draft{}, false
+	}
+
+	return userID, draft, true
+}
+
+// parsePublishAt validates an optional publish_at. A nil value means
+// "publish now"; otherwise it has to be in the future.
+func parsePublishAt(publishAt *time.Time) (sql.NullTime, error) {
+	if publishAt == nil {
+		return sql.NullTime{}, nil
+	}
+	if !publishAt.After(time.Now()) {
+		return sql.NullTime{}, errors.New("publish_at must be in the future")
+	}
+	// Stored as TIMESTAMP without time zone, like every other column.
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+}
+
+func newDraft(d database.Draft) Draft {
+	return Draft{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+		Body:      d.Body,
+	}
+}
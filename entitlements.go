@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Plans a user can be on.
+const (
+	planFree      = "free"
+	planChirpyRed = "chirpy_red"
+)
+
+// Entitlements is what a plan allows. Handlers check these instead of
+// looking at the plan itself.
+type Entitlements struct {
+	Plan           string `json:"plan"`
+	MaxChirpLength int    `json:"max_chirp_length"`
+	Polls          bool   `json:"polls"`
+	ChirpsPerHour  int    `json:"chirps_per_hour"`
+}
+
+var planEntitlements = map[string]Entitlements{
+	planFree: {
+		Plan:           planFree,
+		MaxChirpLength: maxChirpLength,
+		Polls:          false,
+		ChirpsPerHour:  30,
+	},
+	planChirpyRed: {
+		Plan:           planChirpyRed,
+		MaxChirpLength: 2 * maxChirpLength,
+		Polls:          true,
+		ChirpsPerHour:  300,
+	},
+}
+
+func entitlementsFor(user database.User) Entitlements {
+	if isChirpyRed(user) {
+		return planEntitlements[planChirpyRed]
+	}
+	return planEntitlements[planFree]
+}
+
+func (cfg *apiConfig) userEntitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error) {
+	user, err := cfg.db.GetUserById(ctx, userID)
+	if err != nil {
+		return Entitlements{}, err
+	}
+	return entitlementsFor(user), nil
+}
+
+// checkChirpRate answers 429 when the user already wrote as many chirps in
+// the last hour as their plan allows.
+func (cfg *apiConfig) checkChirpRate(w http.ResponseWriter, r *http.Request, userID uuid.UUID, ent Entitlements) bool {
+	count, err := cfg.db.CountChirpsSince(r.Context(), database.CountChirpsSinceParams{
+		UserID: userID,
+		Since:  time.Now().UTC().Add(-time.Hour),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot count chirps", err)
+		return false
+	}
+	if count >= int64(ent.ChirpsPerHour) {
+		w.Header().Set("Retry-After", "3600")
+		jsonError(w, http.StatusTooManyRequests, "hourly chirp limit reached", nil)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) getEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	ent, err := cfg.userEntitlements(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Couldn't load user", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, ent)
+}
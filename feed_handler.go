@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	feedSize       = 50
+	feedTitleRunes = 60
+)
+
+// userFeedHandler serves the latest public chirps of a user. Feed readers
+// don't authenticate, so the feed shows what an anonymous visitor would see.
+func (cfg *apiConfig) userFeedHandler(format feedFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := parseUUIDPathValue(w, r, "userID")
+		if !ok {
+			return
+		}
+
+		viewer, err := cfg.loadChirpViewer(r.Context(), uuid.Nil)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+			return
+		}
+
+		// A private or unknown user has no feed.
+		user, err := cfg.db.GetUserById(r.Context(), userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusInternalServerError, "Cannot get user", err)
+			return
+		}
+		if err != nil || user.IsPrivate {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+
+		dbChirps, err := cfg.db.GetChirpsByUserId(r.Context(), userID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "Cannot get chirps by author", err)
+			return
+		}
+		// Filtered before the cut, so that hidden chirps don't shorten it.
+		dbChirps, err = cfg.filterVisible(r.Context(), viewer, dbChirps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "Cannot filter chirps", err)
+			return
+		}
+		// Oldest first: keep the tail, newest first.
+		if len(dbChirps) > feedSize {
+			dbChirps = dbChirps[len(dbChirps)-feedSize:]
+		}
+		for i, j := 0, len(dbChirps)-1; i < j; i, j = i+1, j-1 {
+			dbChirps[i], dbChirps[j] = dbChirps[j], dbChirps[i]
+		}
+
+		base := baseURL(r)
+		cfg.serveFeed(w, r, format, viewer, feed{
+			ID:    "urn:uuid:" + userID.String(),
+			Title: "Chirps by " + userID.String(),
+			Link:  base + "/api/chirps?author_id=" + userID.String(),
+			Self:  base + r.URL.Path,
+		}, dbChirps)
+	}
+}
+
+// hashtagFeedHandler serves the latest public chirps tagged #tag.
+func (cfg *apiConfig) hashtagFeedHandler(format feedFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag := strings.TrimPrefix(r.PathValue("tag"), "#")
+		if !validHashtag.MatchString(tag) {
+			jsonError(w, http.StatusBadRequest, "invalid hashtag", nil)
+			return
+		}
+
+		viewer, err := cfg.loadChirpViewer(r.Context(), uuid.Nil)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+			return
+		}
+
+		dbChirps, err := cfg.db.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+			Tag:        tag,
+			MaxResults: feedSize,
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "Cannot get chirps by hashtag", err)
+			return
+		}
+		// Postgres and Go disagree on what a word character is outside ASCII.
+		tagged := dbChirps[:0]
+		for _, c := range dbChirps {
+			if hasHashtag(c.Body, tag) {
+				tagged = append(tagged, c)
+			}
+		}
+
+		base := baseURL(r)
+		cfg.serveFeed(w, r, format, viewer, feed{
+			ID:    base + "/api/hashtags/" + url.PathEscape(tag),
+			Title: "Chirps tagged #" + tag,
+			Link:  base + r.URL.Path,
+			Self:  base + r.URL.Path,
+		}, tagged)
+	}
+}
+
+// serveFeed fills f with the chirps the viewer may see and writes it.
+// http.ServeContent answers conditional requests from the ETag and
+// Last-Modified headers set here.
+func (cfg *apiConfig) serveFeed(w http.ResponseWriter, r *http.Request, format feedFormat, viewer *chirpViewer, f feed, dbChirps []database.Chirp) {
+	dbChirps, err := cfg.filterVisible(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot filter chirps", err)
+		return
+	}
+
+	base := baseURL(r)
+	for _, c := range dbChirps {
+		// Rechirps have no content of their own.
+		if c.RechirpOfID.Valid {
+			continue
+		}
+		f.Items = append(f.Items, feedItem{
+			ID:        "urn:uuid:" + c.ID.String(),
+			Link:      base + "/api/chirps/" + c.ID.String(),
+			Title:     feedItemTitle(c),
+			Content:   c.Body,
+			Author:    c.UserID.String(),
+			Published: c.CreatedAt,
+			Updated:   c.UpdatedAt,
+		})
+		if c.UpdatedAt.After(f.Updated) {
+			f.Updated = c.UpdatedAt
+		}
+	}
+
+	body, err := format.encode(f)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot encode feed", err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	http.ServeContent(w, r, "feed."+format.Extension, f.Updated.Truncate(time.Second), bytes.NewReader(body))
+}
+
+// feedItemTitle is the start of the body, or the content warning when
+// there is one so that readers don't show the body in their item list.
+func feedItemTitle(c database.Chirp) string {
+	if c.ContentWarning != "" {
+		return "CW: " + c.ContentWarning
+	}
+	if c.Sensitive {
+		return "Sensitive chirp"
+	}
+	if utf8.RuneCountInString(c.Body) <= feedTitleRunes {
+		return c.Body
+	}
+	return string([]rune(c.Body)[:feedTitleRunes]) + "…"
+}
+
+// baseURL rebuilds the scheme and host the client used, for the absolute
+// links feeds require.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type FollowStatus struct {
+	Status string `json:"status"` // "following" or "requested"
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (cfg *apiConfig) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	targetID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+	if targetID == userID {
+		jsonError(w, http.StatusBadRequest, "cannot follow yourself", nil)
+		return
+	}
+
+	target, err := cfg.db.GetUserById(r.Context(), targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load user", err)
+		return
+	}
+
+	blocked, err := cfg.blockedBetween(r.Context(), userID, targetID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to check blocks", err)
+		return
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you cannot follow this user", nil)
+		return
+	}
+
+	if target.IsPrivate {
+		if err := cfg.db.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    targetID,
+		}); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to request follow", err)
+			return
+		}
+		cfg.notify(r.Context(), targetID, notificationFollowRequest, userID, uuid.Nil)
+		jsonResponse(w, http.StatusAccepted, FollowStatus{Status: "requested"})
+		return
+	}
+
+	if err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to follow user", err)
+		return
+	}
+	cfg.notify(r.Context(), targetID, notificationFollow, userID, uuid.Nil)
+	cfg.emitWebhook(r.Context(), targetID, webhookUserFollowed, userFollowedData{FollowerID: userID, FollowedID: targetID})
+
+	jsonResponse(w, http.StatusOK, FollowStatus{Status: "following"})
+}
+
+func (cfg *apiConfig) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	targetID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to unfollow user", err)
+		return
+	}
+
+	// Also cancel a pending request, if any.
+	if _, err := cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: userID,
+		TargetID:    targetID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to cancel follow request", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbRequests, err := cfg.db.GetFollowRequestsForUser(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list follow requests", err)
+		return
+	}
+
+	requests := make([]FollowRequest, 0, len(dbRequests))
+	for _, fr := range dbRequests {
+		requests = append(requests, FollowRequest{
+			RequesterID: fr.RequesterID,
+			CreatedAt:   fr.CreatedAt,
+		})
+	}
+
+	jsonResponse(w, http.StatusOK, requests)
+}
+
+func (cfg *apiConfig) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	requesterID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	approved, err := cfg.db.ApproveFollowRequest(r.Context(), database.ApproveFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to approve follow request", err)
+		return
+	}
+	if approved == 0 {
+		jsonError(w, http.StatusNotFound, "follow request not found", nil)
+		return
+	}
+	cfg.notify(r.Context(), requesterID, notificationFollowAccepted, userID, uuid.Nil)
+	cfg.emitWebhook(r.Context(), userID, webhookUserFollowed, userFollowedData{FollowerID: requesterID, FollowedID: userID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	requesterID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	deleted, err := cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to reject follow request", err)
+		return
+	}
+	if deleted == 0 {
+		jsonError(w, http.StatusNotFound, "follow request not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IsPrivate bool `json:"is_private"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	user, err := cfg.db.SetUserPrivacy(r.Context(), database.SetUserPrivacyParams{
+		ID:        userID,
+		IsPrivate: params.IsPrivate,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "couldn't update privacy", err)
+		return
+	}
+
+	// Going public lets everyone who was waiting in.
+	if !user.IsPrivate {
+		followerIDs, err := cfg.db.ApproveAllFollowRequests(r.Context(), userID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to approve pending requests", err)
+			return
+		}
+		for _, followerID := range followerIDs {
+			cfg.notify(r.Context(), followerID, notificationFollowAccepted, userID, uuid.Nil)
+			cfg.emitWebhook(r.Context(), userID, webhookUserFollowed, userFollowedData{FollowerID: followerID, FollowedID: userID})
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, newUser(user))
+}
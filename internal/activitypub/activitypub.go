@@ -0,0 +1,305 @@
+// Package activitypub holds the ActivityStreams documents chirpy exchanges
+// with the fediverse and a client to fetch remote actors and deliver
+// signed activities to their inboxes.
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/httpsig"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+)
+
+const (
+	ContentType = "application/activity+json"
+	// Also accepted when fetching documents.
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+	Context = "https://www.w3.org/ns/activitystreams"
+	Public  = "https://www.w3.org/ns/activitystreams#Public"
+
+	securityContext = "https://w3id.org/security/v1"
+
+	maxDocumentSize = 1 << 20
+)
+
+// Actor is the part of an actor document chirpy produces and reads.
+type Actor struct {
+	Context           any       `json:"@context,omitempty"`
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	PreferredUsername string    `json:"preferredUsername,omitempty"`
+	Name              string    `json:"name,omitempty"`
+	Inbox             string    `json:"inbox"`
+	Outbox            string    `json:"outbox,omitempty"`
+	Followers         string    `json:"followers,omitempty"`
+	URL               string    `json:"url,omitempty"`
+	PublicKey         PublicKey `json:"publicKey"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Activity is an incoming or outgoing activity. Object is kept raw because
+// it is either an id or an embedded object depending on the sender.
+type Activity struct {
+	Context any             `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  json.RawMessage `json:"object"`
+	To      Audience        `json:"to,omitempty"`
+	CC      Audience        `json:"cc,omitempty"`
+}
+
+// Object is the subset of an object's properties chirpy looks at.
+type Object struct {
+	Context      any       `json:"@context,omitempty"`
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	AttributedTo string    `json:"attributedTo,omitempty"`
+	Actor        string    `json:"actor,omitempty"`
+	Object       string    `json:"object,omitempty"`
+	Content      string    `json:"content,omitempty"`
+	Summary      string    `json:"summary,omitempty"`
+	Sensitive    bool      `json:"sensitive,omitempty"`
+	InReplyTo    string    `json:"inReplyTo,omitempty"`
+	Published    time.Time `json:"published,omitzero"`
+	URL          string    `json:"url,omitempty"`
+	To           Audience  `json:"to,omitempty"`
+	CC           Audience  `json:"cc,omitempty"`
+}
+
+// Audience is a list of recipients, which some servers send as a single
+// string when there is only one.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = Audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// ObjectRef decodes the object of an activity, which is either embedded
+// or only referenced by its id.
+func (a Activity) ObjectRef() (Object, error) {
+	var id string
+	if err := json.Unmarshal(a.Object, &id); err == nil {
+		return Object{ID: id}, nil
+	}
+	var obj Object
+	if err := json.Unmarshal(a.Object, &obj); err != nil {
+		return Object{}, fmt.Errorf("activitypub: invalid object: %w", err)
+	}
+	return obj, nil
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int    `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is a JSON Resource Descriptor (RFC 7033).
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// ActorContext is the @context of actor documents, which embed a key.
+func ActorContext() []string {
+	return []string{Context, securityContext}
+}
+
+// GenerateKey returns a new RSA key pair, PEM-encoded.
+func GenerateKey() (privatePEM, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(key)}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	return privatePEM, publicPEM, nil
+}
+
+func mustPKCS8(key *rsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err) // only fails for unsupported key types
+	}
+	return der
+}
+
+func ParsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("activitypub: invalid private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("activitypub: private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey accepts the PKIX and PKCS#1 encodings found in the wild.
+func ParsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("activitypub: invalid public key PEM")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("activitypub: public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// DeliveryError reports a failed delivery. Permanent failures won't get
+// better by retrying.
+type DeliveryError struct {
+	StatusCode int
+	Permanent  bool
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("activitypub: delivery failed: %v", e.Err)
+	}
+	return fmt.Sprintf("activitypub: delivery failed with status %d", e.StatusCode)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Client talks to remote servers. Their URLs come from remote documents,
+// so they are held to Policy.
+type Client struct {
+	HTTP      *http.Client
+	Policy    safehttp.Policy
+	UserAgent string
+}
+
+func NewClient(userAgent string, policy safehttp.Policy) *Client {
+	return &Client{
+		HTTP:      policy.Client(10 * time.Second),
+		Policy:    policy,
+		UserAgent: userAgent,
+	}
+}
+
+// FetchActor dereferences a remote actor.
+func (c *Client) FetchActor(ctx context.Context, actorURL string) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	if err := c.Policy.CheckURL(req.URL); err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType+", "+LDContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("activitypub: fetching %s: status %d", actorURL, resp.StatusCode)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&actor); err != nil {
+		return Actor{}, fmt.Errorf("activitypub: decoding %s: %w", actorURL, err)
+	}
+	if actor.ID != actorURL {
+		return Actor{}, fmt.Errorf("activitypub: %s describes %s", actorURL, actor.ID)
+	}
+	if actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" || actor.PublicKey.Owner != actor.ID {
+		return Actor{}, fmt.Errorf("activitypub: %s has no usable inbox or key", actorURL)
+	}
+	return actor, nil
+}
+
+// Deliver POSTs a signed activity to an inbox.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte, keyID string, key *rsa.PrivateKey) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return &DeliveryError{Permanent: true, Err: err}
+	}
+	if err := c.Policy.CheckURL(req.URL); err != nil {
+		return &DeliveryError{Permanent: true, Err: err}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+	if err := httpsig.Sign(req, keyID, key, activity); err != nil {
+		return &DeliveryError{Permanent: true, Err: err}
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return &DeliveryError{Err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &DeliveryError{
+		StatusCode: resp.StatusCode,
+		// Client errors won't change on retry, except timeouts and rate limits.
+		Permanent: resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests,
+	}
+}
@@ -0,0 +1,222 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/httpsig"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+)
+
+// localPolicy lets the client reach the httptest servers.
+var localPolicy = safehttp.Policy{AllowPrivate: true, AllowHTTP: true}
+
+// fakeRemote is a remote server with a single actor and its inbox.
+type fakeRemote struct {
+	server   *httptest.Server
+	actor    Actor
+	status   int
+	received chan []byte
+	// Key the inbox expects deliveries to be signed with.
+	senderKey *rsa.PublicKey
+}
+
+func newFakeRemote(t *testing.T) *fakeRemote {
+	t.Helper()
+	f := &fakeRemote{status: http.StatusAccepted, received: make(chan []byte, 1)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(f.actor)
+	})
+	mux.HandleFunc("POST /users/alice/inbox", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, err := httpsig.Verify(r, body, time.Minute, func(string) (*rsa.PublicKey, error) {
+			return f.senderKey, nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		f.received <- body
+		w.WriteHeader(f.status)
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	_, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	id := f.server.URL + "/users/alice"
+	f.actor = Actor{
+		Context: ActorContext(),
+		ID:      id,
+		Type:    "Person",
+		Inbox:   id + "/inbox",
+		PublicKey: PublicKey{
+			ID:           id + "#main-key",
+			Owner:        id,
+			PublicKeyPem: publicPEM,
+		},
+	}
+	return f
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	private, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	public, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+	if !private.PublicKey.Equal(public) {
+		t.Error("public key does not match private key")
+	}
+}
+
+func TestFetchActor(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := NewClient("chirpy-test", localPolicy)
+
+	actor, err := client.FetchActor(context.Background(), remote.actor.ID)
+	if err != nil {
+		t.Fatalf("FetchActor() error = %v", err)
+	}
+	if actor.Inbox != remote.actor.Inbox || actor.PublicKey.ID != remote.actor.PublicKey.ID {
+		t.Errorf("FetchActor() = %+v, want %+v", actor, remote.actor)
+	}
+
+	// A document claiming to be someone else is rejected.
+	remote.actor.ID = "https://elsewhere.example/users/mallory"
+	if _, err := client.FetchActor(context.Background(), remote.server.URL+"/users/alice"); err == nil {
+		t.Error("FetchActor() accepted a document with another id")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := NewClient("chirpy-test", localPolicy)
+
+	privatePEM, publicPEM, _ := GenerateKey()
+	key, _ := ParsePrivateKey(privatePEM)
+	remote.senderKey, _ = ParsePublicKey(publicPEM)
+
+	activity := []byte(`{"type":"Create","actor":"https://chirpy.example/ap/users/1"}`)
+	if err := client.Deliver(context.Background(), remote.actor.Inbox, activity, "https://chirpy.example/ap/users/1#main-key", key); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if got := <-remote.received; string(got) != string(activity) {
+		t.Errorf("inbox received %s, want %s", got, activity)
+	}
+}
+
+func TestDeliverErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wrongKey      bool
+		wantPermanent bool
+	}{
+		{name: "Server error is retried", status: http.StatusInternalServerError, wantPermanent: false},
+		{name: "Rate limit is retried", status: http.StatusTooManyRequests, wantPermanent: false},
+		{name: "Gone is permanent", status: http.StatusGone, wantPermanent: true},
+		{name: "Rejected signature is permanent", status: http.StatusAccepted, wrongKey: true, wantPermanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newFakeRemote(t)
+			remote.status = tt.status
+			client := NewClient("chirpy-test", localPolicy)
+
+			privatePEM, publicPEM, _ := GenerateKey()
+			key, _ := ParsePrivateKey(privatePEM)
+			remote.senderKey, _ = ParsePublicKey(publicPEM)
+			if tt.wrongKey {
+				otherPEM, _, _ := GenerateKey()
+				key, _ = ParsePrivateKey(otherPEM)
+			}
+
+			err := client.Deliver(context.Background(), remote.actor.Inbox, []byte(`{}`), "key", key)
+			var deliveryErr *DeliveryError
+			if !errors.As(err, &deliveryErr) {
+				t.Fatalf("Deliver() error = %v, want a DeliveryError", err)
+			}
+			if deliveryErr.Permanent != tt.wantPermanent {
+				t.Errorf("Permanent = %v, want %v", deliveryErr.Permanent, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestDeliverUnreachable(t *testing.T) {
+	remote := newFakeRemote(t)
+	inbox := remote.actor.Inbox
+	remote.server.Close()
+
+	privatePEM, _, _ := GenerateKey()
+	key, _ := ParsePrivateKey(privatePEM)
+
+	err := NewClient("chirpy-test", localPolicy).Deliver(context.Background(), inbox, []byte(`{}`), "key", key)
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) || deliveryErr.Permanent {
+		t.Errorf("Deliver() error = %v, want a retryable DeliveryError", err)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := NewClient("chirpy-test", safehttp.Policy{AllowHTTP: true})
+
+	if _, err := client.FetchActor(context.Background(), remote.actor.ID); !errors.Is(err, safehttp.ErrAddress) {
+		t.Errorf("FetchActor() error = %v, want ErrAddress", err)
+	}
+
+	privatePEM, _, _ := GenerateKey()
+	key, _ := ParsePrivateKey(privatePEM)
+	err := client.Deliver(context.Background(), "https://169.254.169.254/inbox", []byte(`{}`), "key", key)
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) || !deliveryErr.Permanent || !errors.Is(err, safehttp.ErrAddress) {
+		t.Errorf("Deliver() error = %v, want a permanent ErrAddress", err)
+	}
+}
+
+func TestObjectRef(t *testing.T) {
+	byID := Activity{Object: json.RawMessage(`"https://remote.example/notes/1"`)}
+	obj, err := byID.ObjectRef()
+	if err != nil || obj.ID != "https://remote.example/notes/1" || obj.Type != "" {
+		t.Errorf("ObjectRef() = %+v, %v", obj, err)
+	}
+
+	embedded := Activity{Object: json.RawMessage(`{"id":"https://remote.example/follows/1","type":"Follow","object":"https://chirpy.example/ap/users/1"}`)}
+	obj, err = embedded.ObjectRef()
+	if err != nil || obj.Type != "Follow" || obj.Object != "https://chirpy.example/ap/users/1" {
+		t.Errorf("ObjectRef() = %+v, %v", obj, err)
+	}
+
+	single := Activity{Object: json.RawMessage(`{"id":"n","type":"Note","to":"https://www.w3.org/ns/activitystreams#Public"}`)}
+	obj, err = single.ObjectRef()
+	if err != nil || len(obj.To) != 1 || obj.To[0] != Public {
+		t.Errorf("ObjectRef() = %+v, %v", obj, err)
+	}
+
+	invalid := Activity{Object: json.RawMessage(`42`)}
+	if _, err := invalid.ObjectRef(); err == nil {
+		t.Error("ObjectRef() accepted a number")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 	"net/http"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -65,7 +66,7 @@ func TestCheckPassword(t *testing.T) {
 
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	validToken, _ := MakeJWT(userID, "user", "secret", time.Hour)
 
 	tests := []struct {
 		name        string
@@ -111,6 +112,55 @@ func TestValidateJWT(t *testing.T) {
 	}
 }
 
+func TestParseJWTExpiry(t *testing.T) {
+	userID := uuid.New()
+	before := time.Now().Add(time.Hour).Truncate(time.Second)
+	token, _ := MakeJWT(userID, "user", "secret", time.Hour)
+
+	claims, err := ParseJWT(token, "secret")
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.UserID != userID {
+		t.Errorf("ParseJWT() UserID = %v, want %v", claims.UserID, userID)
+	}
+	if claims.ExpiresAt.Before(before) || claims.ExpiresAt.After(before.Add(2*time.Second)) {
+		t.Errorf("ParseJWT() ExpiresAt = %v, want about %v", claims.ExpiresAt, before)
+	}
+
+	expired, _ := MakeJWT(userID, "user", "secret", -time.Minute)
+	if _, err := ParseJWT(expired, "secret"); err == nil {
+		t.Error("ParseJWT() accepted an expired token")
+	}
+}
+
+func TestParseJWTRole(t *testing.T) {
+	userID := uuid.New()
+
+	token, _ := MakeJWT(userID, "moderator", "secret", time.Hour)
+	claims, err := ParseJWT(token, "secret")
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.Role != "moderator" {
+		t.Errorf("ParseJWT() Role = %q, want %q", claims.Role, "moderator")
+	}
+
+	// Tokens issued before roles existed are still accepted.
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	}).SignedString([]byte("secret"))
+	claims, err = ParseJWT(legacy, "secret")
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+	if claims.UserID != userID || claims.Role != "" {
+		t.Errorf("ParseJWT() = %+v, want no role", claims)
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct{
 		name string
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries "t=<unix timestamp>,v1=<hex HMAC-SHA256>".
+// Several v1 entries may be sent while a secret is being rotated.
+const WebhookSignatureHeader = "X-Polka-Signature"
+
+var (
+	ErrNoWebhookSignature      = errors.New("no webhook signature included in request")
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrWebhookTooOld           = errors.New("webhook timestamp outside the tolerance window")
+)
+
+// SignWebhook returns the signature header value for body sent at timestamp.
+// The HMAC covers "<timestamp>.<body>", so a signature can't be reused with
+// another timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(webhookMAC(secret, ts, body))
+}
+
+// VerifyWebhookSignature checks a signature header against the raw body. The
+// timestamp must be within tolerance of now, which bounds how long a
+// captured request can be replayed.
+func VerifyWebhookSignature(header string, body []byte, secret string, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrNoWebhookSignature
+	}
+
+	var (
+		ts         string
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("malformed webhook signature: %q", part)
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("malformed webhook signature: %w", err)
+			}
+			signatures = append(signatures, sig)
+		}
+	}
+	if ts == "" || len(signatures) == 0 {
+		return errors.New("malformed webhook signature: missing timestamp or signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed webhook timestamp: %w", err)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrWebhookTooOld
+	}
+
+	expected := webhookMAC(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+// CheckAPIKey compares API keys in constant time.
+func CheckAPIKey(got, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func webhookMAC(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	now := time.Now()
+	valid := SignWebhook("secret", now, body)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:   "Valid signature",
+			header: valid,
+			body:   body,
+		},
+		{
+			name:   "Rotated secrets",
+			header: valid + ",v1=" + strings.Repeat("00", 32),
+			body:   body,
+		},
+		{
+			name:    "Missing header",
+			header:  "",
+			body:    body,
+			wantErr: ErrNoWebhookSignature,
+		},
+		{
+			name:    "Wrong secret",
+			header:  SignWebhook("other", now, body),
+			body:    body,
+			wantErr: ErrInvalidWebhookSignature,
+		},
+		{
+			name:    "Tampered body",
+			header:  valid,
+			body:    []byte(`{"event":"user.upgraded","data":{"user_id":"00000000-0000-0000-0000-000000000000"}}`),
+			wantErr: ErrInvalidWebhookSignature,
+		},
+		{
+			name:    "Replayed outside the window",
+			header:  SignWebhook("secret", now.Add(-10*time.Minute), body),
+			body:    body,
+			wantErr: ErrWebhookTooOld,
+		},
+		{
+			name:    "Timestamp swapped",
+			header:  strings.Replace(valid, strconv.FormatInt(now.Unix(), 10), strconv.FormatInt(now.Unix()-1, 10), 1),
+			body:    body,
+			wantErr: ErrInvalidWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.header, tt.body, "secret", 5*time.Minute, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWebhookSignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := VerifyWebhookSignature("t=abc,v1=zz", body, "secret", time.Minute, now); err == nil {
+		t.Error("VerifyWebhookSignature() accepted a malformed header")
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	if !CheckAPIKey("key", "key") {
+		t.Error("CheckAPIKey() rejected the right key")
+	}
+	if CheckAPIKey("nope", "key") {
+		t.Error("CheckAPIKey() accepted a wrong key")
+	}
+	if CheckAPIKey("", "") {
+		t.Error("CheckAPIKey() accepted an empty key")
+	}
+}
@@ -7,29 +7,107 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpsSince = `-- name: CountChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1
+  AND rechirp_of_id IS NULL
+  AND created_at >= $2
+`
+
+type CountChirpsSinceParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+// Chirps a user wrote since a given time, scheduled ones included.
+func (q *Queries) CountChirpsSince(ctx context.Context, arg CountChirpsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsSince, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of_id, publish_at, content_warning, sensitive)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	QuoteOfID      uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning string
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuoteOfID,
+		arg.PublishAt,
+		arg.ContentWarning,
+		arg.Sensitive,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+`
+
+type CreateRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+// Returns nothing when the user already rechirped the chirp.
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOfID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,22 +115,37 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
+const deleteRechirp = `-- name: DeleteRechirp :execrows
 DELETE FROM chirps
-WHERE id = $1
+WHERE user_id = $1 AND rechirp_of_id = $2
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
-	return err
+type DeleteRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOfID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
 FROM chirps
 WHERE id = $1
 `
@@ -66,13 +159,21 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
 FROM chirps
+WHERE publish_at IS NULL AND deleted_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -91,6 +192,160 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByHashtag = `-- name: GetChirpsByHashtag :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.rechirp_of_id, chirps.quote_of_id, chirps.publish_at, chirps.content_warning, chirps.sensitive, chirps.deleted_at, chirps.hidden_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.publish_at IS NULL AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND NOT users.is_private
+  AND NOT EXISTS (
+      SELECT 1 FROM suspensions
+      WHERE suspensions.user_id = chirps.user_id
+        AND suspensions.lifted_at IS NULL
+        AND suspensions.ends_at > NOW()
+  )
+  AND chirps.body ~* ('(^|\W)#' || $1::text || '(\W|$)')
+ORDER BY chirps.created_at DESC
+LIMIT $2
+`
+
+type GetChirpsByHashtagParams struct {
+	Tag        string
+	MaxResults int32
+}
+
+// Only returns what anyone may read, so that the limit isn't spent on
+// chirps filtered out afterwards: no hidden chirps, and no chirps of
+// private, suspended or limited users.
+func (q *Queries) GetChirpsByHashtag(ctx context.Context, arg GetChirpsByHashtagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByHashtag, arg.Tag, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUserIDs = `-- name: GetChirpsByUserIDs :many
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+FROM chirps
+WHERE user_id = ANY($1::uuid[]) AND publish_at IS NULL AND deleted_at IS NULL
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetChirpsByUserIDs(ctx context.Context, userIds []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserIDs, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -106,9 +361,9 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUserId = `-- name: GetChirpsByUserId :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
 FROM chirps
-WHERE user_id = $1
+WHERE user_id = $1 AND publish_at IS NULL AND deleted_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -127,6 +382,13 @@ func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -140,3 +402,289 @@ func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2
+`
+
+type GetRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getRechirpCounts = `-- name: GetRechirpCounts :many
+SELECT rechirp_of_id, COUNT(*)
+FROM chirps
+WHERE rechirp_of_id = ANY($1::uuid[]) AND deleted_at IS NULL
+GROUP BY rechirp_of_id
+`
+
+type GetRechirpCountsRow struct {
+	RechirpOfID uuid.NullUUID
+	Count       int64
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, ids []uuid.UUID) ([]GetRechirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpCounts, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpCountsRow
+	for rows.Next() {
+		var i GetRechirpCountsRow
+		if err := rows.Scan(&i.RechirpOfID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledChirpsByUserId = `-- name: GetScheduledChirpsByUserId :many
+SELECT id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+FROM chirps
+WHERE user_id = $1 AND publish_at IS NOT NULL AND deleted_at IS NULL
+ORDER BY publish_at ASC
+`
+
+func (q *Queries) GetScheduledChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirpsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+UPDATE chirps
+SET
+  created_at = NOW(),
+  updated_at = NOW(),
+  publish_at = NULL
+WHERE id IN (
+    SELECT id
+    FROM chirps
+    WHERE publish_at IS NOT NULL AND publish_at <= NOW() AND deleted_at IS NULL
+    ORDER BY publish_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < NOW() - $1::bigint * INTERVAL '1 second'
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, windowSeconds int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, windowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :execrows
+UPDATE chirps
+SET
+  deleted_at = NULL,
+  updated_at = NOW()
+WHERE id = $1
+  AND deleted_at > NOW() - $2::bigint * INTERVAL '1 second'
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	WindowSeconds int64
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreChirp, arg.ID, arg.WindowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setChirpHidden = `-- name: SetChirpHidden :one
+UPDATE chirps
+SET
+  hidden_at  = CASE WHEN $1::boolean THEN COALESCE(hidden_at, NOW()) END,
+  updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+`
+
+type SetChirpHiddenParams struct {
+	Hidden bool
+	ID     uuid.UUID
+}
+
+func (q *Queries) SetChirpHidden(ctx context.Context, arg SetChirpHiddenParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpHidden, arg.Hidden, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const setChirpSensitive = `-- name: SetChirpSensitive :one
+UPDATE chirps
+SET
+  sensitive  = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, rechirp_of_id, quote_of_id, publish_at, content_warning, sensitive, deleted_at, hidden_at
+`
+
+type SetChirpSensitiveParams struct {
+	ID        uuid.UUID
+	Sensitive bool
+}
+
+func (q *Queries) SetChirpSensitive(ctx context.Context, arg SetChirpSensitiveParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpSensitive, arg.ID, arg.Sensitive)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+WITH unpinned AS (
+  UPDATE users
+  SET
+    pinned_chirp_id = NULL,
+    updated_at      = NOW()
+  WHERE pinned_chirp_id = $1
+)
+UPDATE chirps
+SET
+  deleted_at = NOW(),
+  updated_at = NOW()
+WHERE id = $1
+`
+
+// Unpins the chirp too; restoring it doesn't pin it again.
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
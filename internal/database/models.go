@@ -11,12 +11,158 @@ import (
 	"github.com/google/uuid"
 )
 
+type ActivitypubCursor struct {
+	ID          bool
+	LastEventID int64
+}
+
+type ActivitypubDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	Inbox         string
+	Payload       string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   sql.NullTime
+	FailedAt      sql.NullTime
+}
+
+type ActivitypubKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Action    string
+	ActorID   uuid.NullUUID
+	TargetID  uuid.NullUUID
+	Ip        string
+	UserAgent string
+	Metadata  string
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	RechirpOfID    uuid.NullUUID
+	QuoteOfID      uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning string
+	Sensitive      bool
+	DeletedAt      sql.NullTime
+	HiddenAt       sql.NullTime
+}
+
+type ChirpEvent struct {
+	ID        int64
+	CreatedAt time.Time
+	Kind      string
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Xid       int64
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+}
+
+type ConversationParticipant struct {
+	ConversationID    uuid.UUID
+	UserID            uuid.UUID
+	JoinedAt          time.Time
+	LastReadAt        time.Time
+	LastReadMessageID uuid.NullUUID
+}
+
+type Draft struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
 	Body      string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+type List struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	OwnerID   uuid.UUID
+	Name      string
+	IsPrivate bool
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
 	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Type      string
+	ActorID   uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +174,145 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type RemoteActor struct {
+	ID           string
+	Inbox        string
+	PublicKeyID  string
+	PublicKeyPem string
+	FetchedAt    time.Time
+}
+
+type RemoteFollower struct {
+	UserID           uuid.UUID
+	ActorID          string
+	FollowActivityID string
+	CreatedAt        time.Time
+}
+
+type RemoteNote struct {
+	ID          string
+	UserID      uuid.UUID
+	ActorID     string
+	Content     string
+	InReplyTo   string
+	PublishedAt time.Time
+	ReceivedAt  time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ReporterID uuid.UUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Reason     string
+	Details    string
+	Status     string
+	Action     string
+	Note       string
+	ResolvedBy uuid.NullUUID
+	ResolvedAt sql.NullTime
+}
+
+type RoleChange struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ActorID   uuid.NullUUID
+	OldRole   string
+	NewRole   string
+}
+
+type SubscriptionPeriod struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	StartedAt time.Time
+	EndsAt    time.Time
+	Status    string
+	EndedAt   sql.NullTime
+	EndReason string
+}
+
+type Suspension struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Reason    string
+	EndsAt    time.Time
+	CreatedBy uuid.NullUUID
+	ReportID  uuid.NullUUID
+	Kind      string
+	LiftedAt  sql.NullTime
+	LiftedBy  uuid.NullUUID
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Email             string
+	HashedPassword    string
+	IsChirpyRed       sql.NullBool
+	IsPrivate         bool
+	PinnedChirpID     uuid.NullUUID
+	CollapseSensitive bool
+	Role              string
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type WebhookCursor struct {
+	ID          bool
+	LastEventID int64
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	EndpointID    uuid.UUID
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+}
+
+type WebhookDeliveryAttempt struct {
+	ID          uuid.UUID
+	DeliveryID  uuid.UUID
+	AttemptedAt time.Time
+	StatusCode  int32
+	Error       string
+	DurationMs  int32
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	Url       string
+	Secret    string
+	Events    []string
+}
+
+type WebhookEvent struct {
+	ID          string
+	ReceivedAt  time.Time
+	Event       string
+	Payload     string
+	Status      string
+	Error       string
+	Attempts    int32
+	ProcessedAt sql.NullTime
+	Provider    string
 }
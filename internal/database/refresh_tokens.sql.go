@@ -71,7 +71,7 @@ func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (Ref
 }
 
 const getUserByRefreshToken = `-- name: GetUserByRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.is_private, users.pinned_chirp_id, users.collapse_sensitive, users.role
 FROM refresh_tokens
 JOIN users ON refresh_tokens.user_id = users.id
 WHERE refresh_tokens.token = $1
@@ -87,6 +87,10 @@ func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (User
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
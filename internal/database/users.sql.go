@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -20,7 +21,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 `
 
 type CreateUserParams struct {
@@ -38,12 +39,43 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
+	)
+	return i, err
+}
+
+const downgradeFromChirpyRed = `-- name: DowngradeFromChirpyRed :one
+UPDATE users
+SET
+  is_chirpy_red = FALSE,
+  updated_at     = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
+`
+
+func (q *Queries) DowngradeFromChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, downgradeFromChirpyRed, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getLastUser = `-- name: GetLastUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 FROM users
 ORDER BY created_at ASC
 LIMIT 1
@@ -59,12 +91,16 @@ func (q *Queries) GetLastUser(ctx context.Context) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 FROM users
 WHERE email = $1
 `
@@ -79,12 +115,16 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 FROM users
 WHERE id = $1
 `
@@ -99,14 +139,175 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
+FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.IsPrivate,
+			&i.PinnedChirpID,
+			&i.CollapseSensitive,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setCollapseSensitive = `-- name: SetCollapseSensitive :one
+UPDATE users
+SET
+  collapse_sensitive = $2,
+  updated_at         = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
+`
+
+type SetCollapseSensitiveParams struct {
+	ID                uuid.UUID
+	CollapseSensitive bool
+}
+
+func (q *Queries) SetCollapseSensitive(ctx context.Context, arg SetCollapseSensitiveParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setCollapseSensitive, arg.ID, arg.CollapseSensitive)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
+	)
+	return i, err
+}
+
+const setPinnedChirp = `-- name: SetPinnedChirp :one
+UPDATE users
+SET
+  pinned_chirp_id = $2,
+  updated_at      = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
+`
+
+type SetPinnedChirpParams struct {
+	ID            uuid.UUID
+	PinnedChirpID uuid.NullUUID
+}
+
+func (q *Queries) SetPinnedChirp(ctx context.Context, arg SetPinnedChirpParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPinnedChirp, arg.ID, arg.PinnedChirpID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
+	)
+	return i, err
+}
+
+const setUserPrivacy = `-- name: SetUserPrivacy :one
+UPDATE users
+SET
+  is_private = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
+`
+
+type SetUserPrivacyParams struct {
+	ID        uuid.UUID
+	IsPrivate bool
+}
+
+func (q *Queries) SetUserPrivacy(ctx context.Context, arg SetUserPrivacyParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserPrivacy, arg.ID, arg.IsPrivate)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
 
+const unpinChirp = `-- name: UnpinChirp :execrows
+UPDATE users
+SET
+  pinned_chirp_id = NULL,
+  updated_at      = NOW()
+WHERE id = $1 AND pinned_chirp_id = $2
+`
+
+type UnpinChirpParams struct {
+	ID            uuid.UUID
+	PinnedChirpID uuid.NullUUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unpinChirp, arg.ID, arg.PinnedChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserByID = `-- name: UpdateUserByID :one
 UPDATE users SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 `
 
 type UpdateUserByIDParams struct {
@@ -125,6 +326,10 @@ func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams)
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
@@ -135,7 +340,7 @@ SET
   is_chirpy_red = TRUE,
   updated_at     = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_private, pinned_chirp_id, collapse_sensitive, role
 `
 
 func (q *Queries) UpgradeToChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -148,6 +353,10 @@ func (q *Queries) UpgradeToChirpyRed(ctx context.Context, id uuid.UUID) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
+		&i.PinnedChirpID,
+		&i.CollapseSensitive,
+		&i.Role,
 	)
 	return i, err
 }
@@ -0,0 +1,208 @@
+// Package httpsig signs and verifies HTTP requests with the "Signing HTTP
+// Messages" draft (draft-cavage-http-signatures-12) as used across the
+// fediverse: RSA-SHA256 over (request-target), host, date and digest.
+package httpsig
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const requestTarget = "(request-target)"
+
+var (
+	ErrMissingSignature = errors.New("httpsig: missing Signature header")
+	ErrInvalidSignature = errors.New("httpsig: invalid signature")
+)
+
+// Signature is a parsed Signature header.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// Digest returns the value of the Digest header for body.
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign sets the Date and, for requests with a body, Digest headers of req,
+// then signs them along with the request target and host.
+func Sign(req *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{requestTarget, "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest")
+	}
+
+	signed, err := signingString(req, headers)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Verify checks the signature of req, whose body has already been read
+// into body. lookup returns the public key for the signature's keyId.
+// Signatures must cover the request target, host and date, plus the digest
+// when there is a body, and be dated within maxSkew of now.
+func Verify(req *http.Request, body []byte, maxSkew time.Duration, lookup func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	sig, err := Parse(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return "", fmt.Errorf("httpsig: unsupported algorithm %q", sig.Algorithm)
+	}
+
+	required := []string{requestTarget, "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !contains(sig.Headers, h) {
+			return "", fmt.Errorf("httpsig: %s is not signed", h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", fmt.Errorf("httpsig: invalid Date header: %w", err)
+	}
+	if skew := time.Since(date); skew > maxSkew || skew < -maxSkew {
+		return "", errors.New("httpsig: Date header is too far from now")
+	}
+
+	if contains(sig.Headers, "digest") {
+		got := req.Header.Get("Digest")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(Digest(body))) != 1 {
+			return "", errors.New("httpsig: Digest does not match the body")
+		}
+	}
+
+	key, err := lookup(sig.KeyID)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := signingString(req, sig.Headers)
+	if err != nil {
+		return "", err
+	}
+	hashed := sha256.Sum256([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.Signature); err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	return sig.KeyID, nil
+}
+
+// Parse parses a Signature header.
+func Parse(header string) (Signature, error) {
+	if header == "" {
+		return Signature{}, ErrMissingSignature
+	}
+
+	params := map[string]string{}
+	for _, part := range splitParams(header) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Signature{}, fmt.Errorf("httpsig: malformed parameter %q", part)
+		}
+		params[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	sig := Signature{
+		KeyID:     params["keyId"],
+		Algorithm: params["algorithm"],
+		Headers:   strings.Fields(strings.ToLower(params["headers"])),
+	}
+	if sig.KeyID == "" {
+		return Signature{}, errors.New("httpsig: missing keyId")
+	}
+	// Without a headers parameter, only the Date is signed.
+	if len(sig.Headers) == 0 {
+		sig.Headers = []string{"date"}
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(raw) == 0 {
+		return Signature{}, errors.New("httpsig: malformed signature")
+	}
+	sig.Signature = raw
+
+	return sig, nil
+}
+
+// splitParams splits on the commas that are outside quoted strings.
+func splitParams(header string) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+	for i, c := range header {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, header[start:])
+}
+
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		switch h {
+		case requestTarget:
+			lines = append(lines, fmt.Sprintf("%s: %s %s", requestTarget, strings.ToLower(req.Method), req.URL.RequestURI()))
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			lines = append(lines, "host: "+host)
+		default:
+			values := req.Header.Values(h)
+			if len(values) == 0 {
+				return "", fmt.Errorf("httpsig: signed header %s is missing", h)
+			}
+			lines = append(lines, h+": "+strings.Join(values, ", "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
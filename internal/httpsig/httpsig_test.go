@@ -0,0 +1,159 @@
+package httpsig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// signedRequest signs a request as a client would, then rebuilds it as a
+// server receives it.
+func signedRequest(t *testing.T, key *rsa.PrivateKey, body []byte) *http.Request {
+	t.Helper()
+	out, _ := http.NewRequest(http.MethodPost, "https://example.com/ap/users/1/inbox?x=1", bytes.NewReader(body))
+	if err := Sign(out, "https://remote.example/actor#main-key", key, body); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	in := httptest.NewRequest(http.MethodPost, "/ap/users/1/inbox?x=1", bytes.NewReader(body))
+	in.Host = "example.com"
+	for name, values := range out.Header {
+		in.Header[name] = values
+	}
+	return in
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key := newKey(t)
+	otherKey := newKey(t)
+	body := []byte(`{"type":"Follow"}`)
+
+	lookup := func(pub *rsa.PublicKey) func(string) (*rsa.PublicKey, error) {
+		return func(string) (*rsa.PublicKey, error) { return pub, nil }
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(r *http.Request) []byte
+		pub     *rsa.PublicKey
+		skew    time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "Valid signature",
+			tamper:  func(r *http.Request) []byte { return body },
+			pub:     &key.PublicKey,
+			skew:    time.Minute,
+			wantErr: false,
+		},
+		{
+			name:    "Wrong key",
+			tamper:  func(r *http.Request) []byte { return body },
+			pub:     &otherKey.PublicKey,
+			skew:    time.Minute,
+			wantErr: true,
+		},
+		{
+			name:    "Tampered body",
+			tamper:  func(r *http.Request) []byte { return []byte(`{"type":"Delete"}`) },
+			pub:     &key.PublicKey,
+			skew:    time.Minute,
+			wantErr: true,
+		},
+		{
+			name: "Tampered path",
+			tamper: func(r *http.Request) []byte {
+				r.URL.Path = "/ap/users/2/inbox"
+				return body
+			},
+			pub:     &key.PublicKey,
+			skew:    time.Minute,
+			wantErr: true,
+		},
+		{
+			name: "Stale date",
+			tamper: func(r *http.Request) []byte {
+				time.Sleep(1100 * time.Millisecond)
+				return body
+			},
+			pub:     &key.PublicKey,
+			skew:    time.Second,
+			wantErr: true,
+		},
+		{
+			name: "Missing signature",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Del("Signature")
+				return body
+			},
+			pub:     &key.PublicKey,
+			skew:    time.Minute,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signedRequest(t, key, body)
+			received := tt.tamper(r)
+
+			keyID, err := Verify(r, received, tt.skew, lookup(tt.pub))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && keyID != "https://remote.example/actor#main-key" {
+				t.Errorf("Verify() keyID = %q", keyID)
+			}
+		})
+	}
+}
+
+func TestVerifyRequiresSignedDigest(t *testing.T) {
+	key := newKey(t)
+	body := []byte(`{"type":"Follow"}`)
+
+	// Signed without a body, so the digest is not covered.
+	r := signedRequest(t, key, nil)
+	r.Header.Set("Digest", Digest(body))
+
+	_, err := Verify(r, body, time.Minute, func(string) (*rsa.PublicKey, error) { return &key.PublicKey, nil })
+	if err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Verify() error = %v, want digest not signed", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	header := `keyId="https://a.example/u#main-key",algorithm="rsa-sha256",headers="(request-target) host date",signature="c2ln"`
+	sig, err := Parse(header)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if sig.KeyID != "https://a.example/u#main-key" || sig.Algorithm != "rsa-sha256" {
+		t.Errorf("Parse() = %+v", sig)
+	}
+	if got := strings.Join(sig.Headers, " "); got != "(request-target) host date" {
+		t.Errorf("Parse() headers = %q", got)
+	}
+	if string(sig.Signature) != "sig" {
+		t.Errorf("Parse() signature = %q, want %q", sig.Signature, "sig")
+	}
+
+	if _, err := Parse(""); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("Parse(\"\") error = %v, want ErrMissingSignature", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Package payments abstracts the providers chirpy sells Chirpy Red through.
+// Each provider authenticates its own webhooks and translates its events
+// into the subscription events chirpy understands.
+package payments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subscription event types.
+const (
+	SubscriptionStarted  = "subscription.started"
+	SubscriptionRenewed  = "subscription.renewed"
+	PaymentFailed        = "subscription.payment_failed"
+	SubscriptionCanceled = "subscription.canceled"
+	SubscriptionExpired  = "subscription.expired"
+)
+
+var ErrUnauthorized = errors.New("payments: webhook is not authentic")
+
+// Event is a provider event about a user's subscription. Type is empty for
+// events chirpy doesn't act upon.
+type Event struct {
+	// Unique per event: redeliveries of an event carry the same id.
+	ID string
+	// The event name as sent by the provider.
+	Name   string
+	Type   string
+	UserID uuid.UUID
+	// End of the paid period, when the provider tells it.
+	PeriodEnd time.Time
+}
+
+// Provider receives webhooks from a payment provider.
+type Provider interface {
+	Name() string
+	// Authenticate checks that a webhook request, whose body was already
+	// read, comes from the provider. Failures wrap ErrUnauthorized.
+	Authenticate(r *http.Request, body []byte) error
+	// DecodeEvent parses an authenticated webhook body.
+	DecodeEvent(body []byte) (Event, error)
+}
+
+// bodyID identifies events sent without an id by a hash of their body, which
+// is the same on every redelivery.
+func bodyID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package payments
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func webhookRequest(body []byte, header http.Header) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	return r
+}
+
+func TestPolkaAuthenticate(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	signed := http.Header{auth.WebhookSignatureHeader: {auth.SignWebhook("secret", time.Now(), body)}}
+	badlySigned := http.Header{auth.WebhookSignatureHeader: {auth.SignWebhook("other", time.Now(), body)}}
+	apiKey := http.Header{"Authorization": {"ApiKey key"}}
+	both := http.Header{"Authorization": {"ApiKey key"}, auth.WebhookSignatureHeader: badlySigned[auth.WebhookSignatureHeader]}
+
+	tests := []struct {
+		name    string
+		mode    string
+		header  http.Header
+		wantErr bool
+	}{
+		{name: "API key mode accepts the key", mode: PolkaAuthAPIKey, header: apiKey},
+		{name: "API key mode ignores signatures", mode: PolkaAuthAPIKey, header: signed, wantErr: true},
+		{name: "Signature mode accepts signatures", mode: PolkaAuthSignature, header: signed},
+		{name: "Signature mode rejects the key", mode: PolkaAuthSignature, header: apiKey, wantErr: true},
+		{name: "Both accepts the key", mode: PolkaAuthBoth, header: apiKey},
+		{name: "Both accepts signatures", mode: PolkaAuthBoth, header: signed},
+		{name: "Bad signature can't fall back to the key", mode: PolkaAuthBoth, header: both, wantErr: true},
+		{name: "Nothing", mode: PolkaAuthBoth, header: http.Header{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Polka{APIKey: "key", Secret: "secret", AuthMode: tt.mode}
+			err := p.Authenticate(webhookRequest(body, tt.header), body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("Authenticate() error = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestPolkaDecodeEvent(t *testing.T) {
+	p := &Polka{}
+
+	ev, err := p.DecodeEvent([]byte(`{"id":"evt_1","event":"user.downgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`))
+	if err != nil {
+		t.Fatalf("DecodeEvent() error = %v", err)
+	}
+	if ev.ID != "evt_1" || ev.Name != "user.downgraded" || ev.Type != SubscriptionCanceled {
+		t.Errorf("DecodeEvent() = %+v", ev)
+	}
+
+	body := []byte(`{"event":"user.created","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	first, _ := p.DecodeEvent(body)
+	again, _ := p.DecodeEvent(body)
+	if first.Type != "" || first.ID == "" || first.ID != again.ID {
+		t.Errorf("DecodeEvent() = %+v then %+v, want an ignored event with a stable id", first, again)
+	}
+}
+
+func TestFakeCheckout(t *testing.T) {
+	f := &Fake{Secret: "secret"}
+	userID := uuid.New()
+	periodEnd := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	body, header, err := f.Checkout(userID, SubscriptionStarted, periodEnd)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if err := f.Authenticate(webhookRequest(body, header), body); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	ev, err := f.DecodeEvent(body)
+	if err != nil {
+		t.Fatalf("DecodeEvent() error = %v", err)
+	}
+	if ev.Type != SubscriptionStarted || ev.UserID != userID || !ev.PeriodEnd.Equal(periodEnd) {
+		t.Errorf("DecodeEvent() = %+v", ev)
+	}
+
+	other := &Fake{Secret: "other"}
+	if err := other.Authenticate(webhookRequest(body, header), body); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Authenticate() error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Package safehttp builds HTTP clients for URLs chosen by users or remote
+// servers. Those must not be able to make chirpy reach its own network:
+// addresses are checked once resolved, when dialing, so a hostname can't
+// point somewhere else than it did when it was validated.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 3
+
+var (
+	ErrScheme           = errors.New("safehttp: URL must use https")
+	ErrAddress          = errors.New("safehttp: address is not public")
+	ErrTooManyRedirects = errors.New("safehttp: too many redirects")
+	errNoHost           = errors.New("safehttp: URL has no host")
+)
+
+// Policy tells which URLs are allowed. The zero value only allows https to
+// public addresses.
+type Policy struct {
+	// AllowPrivate lets requests reach loopback, private and other
+	// non-public addresses, for local development.
+	AllowPrivate bool
+	// AllowHTTP accepts plain http URLs besides https.
+	AllowHTTP bool
+}
+
+// nonPublic lists the special-purpose ranges netip has no method for.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// IsPublic reports whether ip is a globally routable unicast address.
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL rejects URLs with a scheme the policy doesn't allow, and those
+// whose host is a literal non-public address. Hostnames are only checked
+// when dialing.
+func (p Policy) CheckURL(u *url.URL) error {
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && p.AllowHTTP:
+	default:
+		return ErrScheme
+	}
+	if u.Hostname() == "" {
+		return errNoHost
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !p.AllowPrivate && !IsPublic(ip) {
+		return ErrAddress
+	}
+	return nil
+}
+
+// Client returns an HTTP client enforcing the policy on every connection
+// and redirect. It ignores proxy settings, which would dial on its behalf.
+func (p Policy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if p.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !IsPublic(ip) {
+				return fmt.Errorf("%w: %s", ErrAddress, ip)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return p.CheckURL(req.URL)
+		},
+	}
+}
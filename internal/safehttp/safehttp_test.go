@@ -0,0 +1,95 @@
+package safehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:4700::1111", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "::1"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "64:ff9b::a9fe:a9fe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := IsPublic(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Errorf("IsPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		url     string
+		wantErr error
+	}{
+		{name: "https", url: "https://example.com/inbox"},
+		{name: "http", url: "http://example.com/inbox", wantErr: ErrScheme},
+		{name: "http allowed", policy: Policy{AllowHTTP: true}, url: "http://example.com/inbox"},
+		{name: "other scheme", policy: Policy{AllowHTTP: true}, url: "file:///etc/passwd", wantErr: ErrScheme},
+		{name: "metadata address", url: "https://169.254.169.254/latest", wantErr: ErrAddress},
+		{name: "loopback allowed", policy: Policy{AllowPrivate: true}, url: "https://127.0.0.1/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			if err := tt.policy.CheckURL(u); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckURL(%s) error = %v, want %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Policy{AllowHTTP: true}.Client(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrAddress) {
+		t.Errorf("Get() error = %v, want ErrAddress", err)
+	}
+
+	resp, err := Policy{AllowHTTP: true, AllowPrivate: true}.Client(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestClientLimitsRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := Policy{AllowHTTP: true, AllowPrivate: true}.Client(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Get() error = %v, want ErrTooManyRedirects", err)
+	}
+}
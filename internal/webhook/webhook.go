@@ -0,0 +1,87 @@
+// Package webhook delivers signed JSON events to the endpoints registered
+// by chirpy users. Requests are signed like the Polka webhooks chirpy
+// receives, so receivers can verify them with auth.VerifyWebhookSignature.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+)
+
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+
+	maxResponseSize = 1 << 16
+)
+
+// StatusError reports a response outside of the 2xx range.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook: endpoint answered with status %d", e.StatusCode)
+}
+
+// Result describes an attempt, successful or not. StatusCode is 0 when no
+// response was received.
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// Client sends webhooks. Endpoint URLs are chosen by users, so they are
+// held to Policy.
+type Client struct {
+	HTTP      *http.Client
+	Policy    safehttp.Policy
+	UserAgent string
+}
+
+func NewClient(userAgent string, policy safehttp.Policy) *Client {
+	return &Client{
+		HTTP:      policy.Client(10 * time.Second),
+		Policy:    policy,
+		UserAgent: userAgent,
+	}
+}
+
+// Send POSTs payload to url, signed with secret.
+func (c *Client) Send(ctx context.Context, url, secret, deliveryID, event string, payload []byte) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return Result{}, err
+	}
+	if err := c.Policy.CheckURL(req.URL); err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, auth.SignWebhook(secret, time.Now(), payload))
+
+	start := time.Now()
+	resp, err := c.HTTP.Do(req)
+	result := Result{Duration: time.Since(start)}
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+)
+
+// localPolicy lets the client reach the httptest servers.
+var localPolicy = safehttp.Policy{AllowPrivate: true, AllowHTTP: true}
+
+type received struct {
+	event, delivery string
+	body            []byte
+	sigErr          error
+}
+
+// newReceiver starts an endpoint answering status and reporting what it
+// received.
+func newReceiver(t *testing.T, secret string, status int) (*httptest.Server, chan received) {
+	t.Helper()
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{
+			event:    r.Header.Get(EventHeader),
+			delivery: r.Header.Get(DeliveryHeader),
+			body:     body,
+			sigErr:   auth.VerifyWebhookSignature(r.Header.Get(SignatureHeader), body, secret, time.Minute, time.Now()),
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestSend(t *testing.T) {
+	server, got := newReceiver(t, "secret", http.StatusNoContent)
+	payload := []byte(`{"type":"chirp.created"}`)
+
+	result, err := NewClient("chirpy-test", localPolicy).Send(context.Background(), server.URL, "secret", "d1", "chirp.created", payload)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if result.StatusCode != http.StatusNoContent {
+		t.Errorf("Send() status = %d, want %d", result.StatusCode, http.StatusNoContent)
+	}
+
+	r := <-got
+	if r.sigErr != nil {
+		t.Errorf("receiver rejected the signature: %v", r.sigErr)
+	}
+	if r.event != "chirp.created" || r.delivery != "d1" || string(r.body) != string(payload) {
+		t.Errorf("receiver got event %q, delivery %q, body %s", r.event, r.delivery, r.body)
+	}
+}
+
+func TestSendWrongSecret(t *testing.T) {
+	server, got := newReceiver(t, "secret", http.StatusOK)
+
+	if _, err := NewClient("chirpy-test", localPolicy).Send(context.Background(), server.URL, "other", "d1", "ping", []byte(`{}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if r := <-got; !errors.Is(r.sigErr, auth.ErrInvalidWebhookSignature) {
+		t.Errorf("receiver signature error = %v, want ErrInvalidWebhookSignature", r.sigErr)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	server, _ := newReceiver(t, "secret", http.StatusInternalServerError)
+
+	result, err := NewClient("chirpy-test", localPolicy).Send(context.Background(), server.URL, "secret", "d1", "ping", []byte(`{}`))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Send() error = %v, want a StatusError with status 500", err)
+	}
+	if result.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Send() status = %d, want 500", result.StatusCode)
+	}
+
+	closed, _ := newReceiver(t, "secret", http.StatusOK)
+	closed.Close()
+	result, err = NewClient("chirpy-test", localPolicy).Send(context.Background(), closed.URL, "secret", "d1", "ping", []byte(`{}`))
+	if err == nil || result.StatusCode != 0 {
+		t.Errorf("Send() = %+v, %v, want a connection error", result, err)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	server, got := newReceiver(t, "secret", http.StatusOK)
+	client := NewClient("chirpy-test", safehttp.Policy{AllowHTTP: true})
+
+	for _, url := range []string{server.URL, "http://169.254.169.254/hook"} {
+		result, err := client.Send(context.Background(), url, "secret", "d1", "ping", []byte(`{}`))
+		if !errors.Is(err, safehttp.ErrAddress) || result.StatusCode != 0 {
+			t.Errorf("Send(%q) = %+v, %v, want ErrAddress", url, result, err)
+		}
+	}
+	select {
+	case <-got:
+		t.Error("a refused delivery reached the endpoint")
+	default:
+	}
+}
@@ -0,0 +1,257 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455, section 1.3.
+	got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	if want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("AcceptKey() = %q, want %q", got, want)
+	}
+}
+
+// testClient speaks just enough of the protocol to drive the server.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, handler func(*Conn)) *testClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		handler(conn)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET / HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	return &testClient{t: t, conn: conn, br: br}
+}
+
+func (c *testClient) writeFrame(fin bool, opcode int, payload []byte, masked bool) {
+	c.t.Helper()
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	default:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	data := append([]byte(nil), payload...)
+	if masked {
+		mask := [4]byte{1, 2, 3, 4}
+		frame = append(frame, mask[:]...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	frame = append(frame, data...)
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+func (c *testClient) readFrame() (int, []byte) {
+	c.t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		c.t.Fatal("server frames must not be masked")
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("read payload: %v", err)
+	}
+	return int(header[0] & 0x0F), payload
+}
+
+func echo(conn *Conn) {
+	for {
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(op, msg, time.Now().Add(time.Second))
+	}
+}
+
+func TestEcho(t *testing.T) {
+	c := dial(t, echo)
+
+	c.writeFrame(true, OpText, []byte("hello"), true)
+	op, payload := c.readFrame()
+	if op != OpText || string(payload) != "hello" {
+		t.Errorf("got (%d, %q), want (%d, %q)", op, payload, OpText, "hello")
+	}
+
+	long := strings.Repeat("x", 300)
+	c.writeFrame(true, OpText, []byte(long), true)
+	if _, payload := c.readFrame(); string(payload) != long {
+		t.Errorf("extended length payload not echoed back")
+	}
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	c := dial(t, echo)
+
+	c.writeFrame(false, OpText, []byte("hel"), true)
+	// Control frames may be interleaved with fragments.
+	c.writeFrame(true, OpPing, []byte("p"), true)
+	c.writeFrame(true, OpContinuation, []byte("lo"), true)
+
+	op, payload := c.readFrame()
+	if op != OpPong || string(payload) != "p" {
+		t.Errorf("got (%d, %q), want pong %q", op, payload, "p")
+	}
+	op, payload = c.readFrame()
+	if op != OpText || string(payload) != "hello" {
+		t.Errorf("got (%d, %q), want text %q", op, payload, "hello")
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     func(c *testClient)
+		wantCode int
+	}{
+		{
+			name:     "Unmasked frame",
+			send:     func(c *testClient) { c.writeFrame(true, OpText, []byte("hi"), false) },
+			wantCode: CloseProtocolError,
+		},
+		{
+			name:     "Unexpected continuation",
+			send:     func(c *testClient) { c.writeFrame(true, OpContinuation, []byte("hi"), true) },
+			wantCode: CloseProtocolError,
+		},
+		{
+			name:     "Invalid UTF-8",
+			send:     func(c *testClient) { c.writeFrame(true, OpText, []byte{0xff, 0xfe}, true) },
+			wantCode: CloseInvalidPayload,
+		},
+		{
+			name:     "Message too big",
+			send:     func(c *testClient) { c.writeFrame(true, OpText, make([]byte, 200), true) },
+			wantCode: CloseMessageTooBig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(chan error, 1)
+			c := dial(t, func(conn *Conn) {
+				conn.MaxMessageSize = 100
+				_, _, err := conn.ReadMessage()
+				errs <- err
+			})
+			tt.send(c)
+
+			op, payload := c.readFrame()
+			if op != OpClose {
+				t.Fatalf("opcode = %d, want close", op)
+			}
+			if code := int(binary.BigEndian.Uint16(payload)); code != tt.wantCode {
+				t.Errorf("close code = %d, want %d", code, tt.wantCode)
+			}
+
+			var closeErr *CloseError
+			if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != tt.wantCode {
+				t.Errorf("ReadMessage() error = %v, want close code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	errs := make(chan error, 1)
+	c := dial(t, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		errs <- err
+	})
+
+	payload := binary.BigEndian.AppendUint16(nil, CloseGoingAway)
+	c.writeFrame(true, OpClose, append(payload, "bye"...), true)
+
+	op, reply := c.readFrame()
+	if op != OpClose || int(binary.BigEndian.Uint16(reply)) != CloseGoingAway {
+		t.Errorf("got (%d, %v), want close %d echoed back", op, reply, CloseGoingAway)
+	}
+
+	var closeErr *CloseError
+	err := <-errs
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Reason != "bye" {
+		t.Errorf("ReadMessage() error = %v, want close %d (bye)", err, CloseGoingAway)
+	}
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := Upgrade(w, r); err == nil {
+		t.Fatal("Upgrade() succeeded on a plain request")
+	}
+	if w.Code != http.StatusUpgradeRequired {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUpgradeRequired)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+const (
+	schedulerInterval = 10 * time.Second
+	schedulerBatch    = 100
+
+	purgeInterval = time.Hour
+)
+
+// runEvery runs job on every tick until ctx is done. Failures are only
+// logged: the state lives in Postgres, so the next tick picks up whatever
+// was left behind, including after a restart.
+func runEvery(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("job %q failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff is the delay before retrying after attempts failures: base after
+// the first one, doubling after each of the next ones, up to max.
+func backoff(base, max time.Duration, attempts int32) time.Duration {
+	delay := base << attempts
+	if delay <= 0 || delay > max {
+		return max
+	}
+	return delay
+}
+
+// publishDueChirps publishes scheduled chirps whose time has come. The
+// query locks rows with FOR UPDATE SKIP LOCKED, so several instances can
+// run it concurrently without publishing a chirp twice.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) error {
+	for {
+		published, err := cfg.db.PublishDueChirps(ctx, schedulerBatch)
+		if err != nil {
+			return err
+		}
+		if len(published) > 0 {
+			log.Printf("published %d scheduled chirps", len(published))
+		}
+		for _, chirp := range published {
+			if err := cfg.notifyQuote(ctx, chirp); err != nil {
+				return err
+			}
+		}
+		if len(published) < schedulerBatch {
+			return nil
+		}
+	}
+}
+
+// purgeDeletedChirps hard-deletes soft-deleted chirps whose restore window
+// has passed.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) error {
+	purged, err := cfg.db.PurgeDeletedChirps(ctx, int64(cfg.ChirpRestoreWindow.Seconds()))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Printf("purged %d deleted chirps", purged)
+	}
+	return nil
+}
+
+// pruneChirpEvents drops stream events too old to be worth replaying.
+func (cfg *apiConfig) pruneChirpEvents(ctx context.Context) error {
+	_, err := cfg.db.PruneChirpEvents(ctx, int64(chirpEventRetention.Seconds()))
+	return err
+}
+
+func (cfg *apiConfig) notifyQuote(ctx context.Context, chirp database.Chirp) error {
+	if !chirp.QuoteOfID.Valid {
+		return nil
+	}
+	quoted, err := cfg.db.GetChirp(ctx, chirp.QuoteOfID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cfg.notify(ctx, quoted.UserID, notificationQuote, chirp.UserID, chirp.ID)
+	return nil
+}
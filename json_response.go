@@ -22,7 +22,13 @@ func jsonError(w http.ResponseWriter, code int, msg string, err error) {
 }
 
 func jsonResponse(w http.ResponseWriter, code int, payload interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	jsonResponseAs(w, code, "application/json", payload)
+}
+
+// jsonResponseAs is jsonResponse for JSON-based media types such as
+// application/activity+json.
+func jsonResponseAs(w http.ResponseWriter, code int, contentType string, payload interface{}) {
+	w.Header().Set("Content-Type", contentType)
 	dat, err := json.Marshal(payload)
 	if err != nil {
 		log.Printf("Error marshalling JSON: %s", err)
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxListNameLength = 64
+
+type List struct {
+	ID        uuid.UUID   `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	OwnerID   uuid.UUID   `json:"owner_id"`
+	Name      string      `json:"name"`
+	IsPrivate bool        `json:"is_private"`
+	MemberIDs []uuid.UUID `json:"member_ids"`
+}
+
+type listParameters struct {
+	Name      string `json:"name"`
+	IsPrivate bool   `json:"is_private"`
+}
+
+func (p *listParameters) validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("list name is required")
+	}
+	if len(p.Name) > maxListNameLength {
+		return errors.New("list name is too long")
+	}
+	return nil
+}
+
+func (cfg *apiConfig) createListHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	var params listParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if err := params.validate(); err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		OwnerID:   userID,
+		Name:      params.Name,
+		IsPrivate: params.IsPrivate,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to create list", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newList(list, []uuid.UUID{}))
+}
+
+func (cfg *apiConfig) listListsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	dbLists, err := cfg.db.GetListsByOwner(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list lists", err)
+		return
+	}
+
+	lists := make([]List, 0, len(dbLists))
+	for _, l := range dbLists {
+		memberIDs, err := cfg.db.GetListMemberIDs(r.Context(), l.ID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load list members", err)
+			return
+		}
+		lists = append(lists, newList(l, memberIDs))
+	}
+
+	jsonResponse(w, http.StatusOK, lists)
+}
+
+func (cfg *apiConfig) getListHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := cfg.optionalUser(w, r)
+	if !ok {
+		return
+	}
+
+	list, ok := cfg.readableList(w, r, viewerID)
+	if !ok {
+		return
+	}
+
+	memberIDs, err := cfg.db.GetListMemberIDs(r.Context(), list.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load list members", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newList(list, memberIDs))
+}
+
+func (cfg *apiConfig) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	var params listParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if err := params.validate(); err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	list, err := cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:        list.ID,
+		Name:      params.Name,
+		IsPrivate: params.IsPrivate,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to update list", err)
+		return
+	}
+
+	memberIDs, err := cfg.db.GetListMemberIDs(r.Context(), list.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load list members", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newList(list, memberIDs))
+}
+
+func (cfg *apiConfig) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.DeleteList(r.Context(), list.ID); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to delete list", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) addListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	if _, err := cfg.db.GetUserById(r.Context(), params.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load user", err)
+		return
+	}
+
+	if err := cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: list.ID,
+		UserID: params.UserID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to add list member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) removeListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to remove list member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := cfg.optionalUser(w, r)
+	if !ok {
+		return
+	}
+
+	list, ok := cfg.readableList(w, r, viewerID)
+	if !ok {
+		return
+	}
+
+	memberIDs, err := cfg.db.GetListMemberIDs(r.Context(), list.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load list members", err)
+		return
+	}
+
+	dbChirps, err := cfg.db.GetChirpsByUserIDs(r.Context(), memberIDs)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot get chirps", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), viewerID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	dbChirps, err = cfg.filterListing(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot filter chirps", err)
+		return
+	}
+
+	chirps, err := cfg.hydrateChirps(r.Context(), viewer, dbChirps)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load chirps", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, collapseRechirps(chirps))
+}
+
+// readableList loads the {listID} list. Private lists only exist for their
+// owner; everyone else gets a 404.
+func (cfg *apiConfig) readableList(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID) (database.List, bool) {
+	listID, ok := parseUUIDPathValue(w, r, "listID")
+	if !ok {
+		return database.List{}, false
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "list not found", err)
+			return database.List{}, false
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load list", err)
+		return database.List{}, false
+	}
+
+	if list.IsPrivate && list.OwnerID != viewerID {
+		jsonError(w, http.StatusNotFound, "list not found", nil)
+		return database.List{}, false
+	}
+
+	return list, true
+}
+
+// ownedList loads the {listID} list for a mutation, which only its owner
+// may perform.
+func (cfg *apiConfig) ownedList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return database.List{}, false
+	}
+
+	list, ok := cfg.readableList(w, r, userID)
+	if !ok {
+		return database.List{}, false
+	}
+
+	if list.OwnerID != userID {
+		jsonError(w, http.StatusForbidden, "not the owner of this list", nil)
+		return database.List{}, false
+	}
+
+	return list, true
+}
+
+func newList(list database.List, memberIDs []uuid.UUID) List {
+	if memberIDs == nil {
+		memberIDs = []uuid.UUID{}
+	}
+	return List{
+		ID:        list.ID,
+		CreatedAt: list.CreatedAt,
+		UpdatedAt: list.UpdatedAt,
+		OwnerID:   list.OwnerID,
+		Name:      list.Name,
+		IsPrivate: list.IsPrivate,
+		MemberIDs: memberIDs,
+	}
+}
@@ -29,16 +29,34 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	user, err := cfg.db.GetUserByEmail(r.Context(), loginDTO.Email)
 	if err != nil {
+		cfg.audit(r, auditEvent{
+			Action:   auditLoginFailed,
+			Metadata: map[string]string{"email": loginDTO.Email, "reason": "unknown_email"},
+		})
 		jsonError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
 	if err := auth.CheckHashedPassword(user.HashedPassword, loginDTO.Password); err != nil {
+		cfg.audit(r, auditEvent{
+			Action:   auditLoginFailed,
+			TargetID: user.ID,
+			Metadata: map[string]string{"email": loginDTO.Email, "reason": "wrong_password"},
+		})
 		jsonError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
-	tokenStr, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+	if !cfg.checkNotSuspended(w, r, user.ID) {
+		cfg.audit(r, auditEvent{
+			Action:   auditLoginFailed,
+			TargetID: user.ID,
+			Metadata: map[string]string{"email": loginDTO.Email, "reason": "suspended"},
+		})
+		return
+	}
+
+	tokenStr, err := auth.MakeJWT(user.ID, user.Role, cfg.JWTSecret, time.Hour)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "Couldn't generate JWT", err)
 		return
@@ -61,20 +79,11 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	IsChirpyRed := false
-	if user.IsChirpyRed.Valid {
-		IsChirpyRed = user.IsChirpyRed.Bool
-	}
+	cfg.audit(r, auditEvent{Action: auditLoginSucceeded, ActorID: user.ID, TargetID: user.ID})
+
+	resp := response{User: newUser(user)}
+	resp.Token = tokenStr
+	resp.RefreshToken = refreshToken
 
-	jsonResponse(w, http.StatusOK, response{
-		User: User{
-			ID:           user.ID,
-			CreatedAt:    user.CreatedAt,
-			UpdatedAt:    user.UpdatedAt,
-			Email:        user.Email,
-			Token:        tokenStr,
-			RefreshToken: refreshToken,
-			IsChirpyRed:  IsChirpyRed,
-		},
-	})
+	jsonResponse(w, http.StatusOK, resp)
 }
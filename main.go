@@ -1,25 +1,48 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
+	"github.com/AymaneIsmail/chirpy/internal/activitypub"
+	"github.com/AymaneIsmail/chirpy/internal/payments"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+	"github.com/AymaneIsmail/chirpy/internal/webhook"
 	// sqlc-generated package (adjust the path to match your project layout)
 	"github.com/AymaneIsmail/chirpy/internal/database"
 )
 
 type apiConfig struct {
 	db             *database.Queries
+	sqlDB          *sql.DB
 	fileServerHits atomic.Int32
 	Platform       string
 	JWTSecret      string
-	PolkaKey       string
+
+	// Payment providers whose webhooks are accepted, by name.
+	paymentProviders map[string]payments.Provider
+
+	// How long a deleted chirp can be restored before it is purged.
+	ChirpRestoreWindow time.Duration
+
+	// Public URL of the server, used in ActivityPub ids.
+	BaseURL string
+
+	chirpHub        *chirpHub
+	notificationHub *notificationHub
+	apClient        *activitypub.Client
+
+	webhookClient *webhook.Client
 }
 
 func main() {
@@ -41,9 +64,67 @@ func main() {
 	}
 
 	polkaKey := os.Getenv("POLKA_KEY")
-	if JWTSecret == "" {
+	polkaWebhookSecret := os.Getenv("POLKA_WEBHOOK_SECRET")
+	polkaAuthMode := os.Getenv("POLKA_AUTH_MODE")
+	if polkaAuthMode == "" {
+		polkaAuthMode = payments.PolkaAuthAPIKey
+		if polkaWebhookSecret != "" {
+			polkaAuthMode = payments.PolkaAuthSignature
+			if polkaKey != "" {
+				polkaAuthMode = payments.PolkaAuthBoth
+			}
+		}
+	}
+	switch polkaAuthMode {
+	case payments.PolkaAuthAPIKey, payments.PolkaAuthSignature, payments.PolkaAuthBoth:
+	default:
+		log.Fatalf("POLKA_AUTH_MODE must be api_key, signature or both: %q", polkaAuthMode)
+	}
+	if polkaAuthMode != payments.PolkaAuthSignature && polkaKey == "" {
 		log.Fatal("POLKA_KEY is not set")
 	}
+	if polkaAuthMode != payments.PolkaAuthAPIKey && polkaWebhookSecret == "" {
+		log.Fatal("POLKA_WEBHOOK_SECRET is not set")
+	}
+
+	paymentProviders := map[string]payments.Provider{}
+	for _, p := range []payments.Provider{
+		&payments.Polka{APIKey: polkaKey, Secret: polkaWebhookSecret, AuthMode: polkaAuthMode},
+	} {
+		paymentProviders[p.Name()] = p
+	}
+	// The fake provider lets anyone upgrade themselves: dev only.
+	if platform == "dev" {
+		secret := os.Getenv("FAKE_PAYMENTS_SECRET")
+		if secret == "" {
+			secret = rand.Text()
+		}
+		fake := &payments.Fake{Secret: secret}
+		paymentProviders[fake.Name()] = fake
+	}
+
+	chirpRestoreWindow := 30 * 24 * time.Hour
+	if raw := os.Getenv("CHIRP_RESTORE_WINDOW"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("CHIRP_RESTORE_WINDOW must be a positive duration (e.g. 720h): %q", raw)
+		}
+		chirpRestoreWindow = d
+	}
+
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+
+	baseURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	// Outbound requests go to URLs chosen by remote servers and users: they
+	// must not reach our own network, except when developing locally.
+	outboundPolicy := safehttp.Policy{
+		AllowPrivate: platform == "dev",
+		AllowHTTP:    platform == "dev",
+	}
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -60,9 +141,27 @@ func main() {
 
 	cfg := apiConfig{
 		db:        dbQueries,
+		sqlDB:     db,
 		Platform:  platform,
 		JWTSecret: JWTSecret,
-		PolkaKey:  polkaKey,
+
+		paymentProviders: paymentProviders,
+
+		ChirpRestoreWindow: chirpRestoreWindow,
+
+		BaseURL: baseURL,
+
+		chirpHub:        newChirpHub(dbQueries),
+		notificationHub: newNotificationHub(),
+		apClient:        activitypub.NewClient("chirpy (+"+baseURL+")", outboundPolicy),
+
+		webhookClient: webhook.NewClient("chirpy-webhooks (+"+baseURL+")", outboundPolicy),
+	}
+
+	if adminEmail != "" {
+		if err := cfg.bootstrapAdmin(context.Background(), adminEmail); err != nil {
+			log.Fatalf("Cannot bootstrap admin: %v", err)
+		}
 	}
 
 	// File server with metrics middleware
@@ -70,24 +169,140 @@ func main() {
 	mux.Handle("/app/", fileHandler)
 
 	// API routes
-	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
-	mux.HandleFunc("POST /admin/reset", cfg.resetUserHandler)
+	mux.HandleFunc("GET /admin/metrics", cfg.requireRole(roleAdmin, cfg.metricsHandler))
+	mux.HandleFunc("POST /admin/reset", cfg.resetHandler())
+	mux.HandleFunc("PUT /admin/users/{userID}/role", cfg.requireRole(roleAdmin, cfg.grantRoleHandler))
+	mux.HandleFunc("DELETE /admin/users/{userID}/role", cfg.requireRole(roleAdmin, cfg.revokeRoleHandler))
+	mux.HandleFunc("GET /admin/role_changes", cfg.requireRole(roleAdmin, cfg.listRoleChangesHandler))
+	mux.HandleFunc("GET /admin/audit", cfg.requireRole(roleAdmin, cfg.listAuditEventsHandler))
+	mux.HandleFunc("PUT /admin/chirps/{chirpID}/sensitive", cfg.requireRole(roleModerator, cfg.setChirpSensitiveHandler))
+	mux.HandleFunc("PUT /admin/chirps/{chirpID}/hidden", cfg.requireRole(roleModerator, cfg.setChirpHiddenHandler))
+	mux.HandleFunc("GET /admin/users/{userID}/suspensions", cfg.requireRole(roleModerator, cfg.listSuspensionsHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/suspensions", cfg.requireRole(roleModerator, cfg.suspendUserHandler))
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspensions", cfg.requireRole(roleModerator, cfg.liftSuspensionsHandler))
+	mux.HandleFunc("GET /admin/reports", cfg.requireRole(roleModerator, cfg.listReportsHandler))
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", cfg.requireRole(roleModerator, cfg.resolveReportHandler))
+	mux.HandleFunc("GET /admin/webhooks/events", cfg.requireRole(roleAdmin, cfg.listWebhookEventsHandler))
+	mux.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", cfg.requireRole(roleAdmin, cfg.replayWebhookEventHandler))
+	mux.HandleFunc("GET /admin/webhooks/endpoints", cfg.requireRole(roleAdmin, cfg.listWebhookEndpointsHandler(true)))
+	mux.HandleFunc("POST /admin/webhooks/endpoints", cfg.requireRole(roleAdmin, cfg.createWebhookEndpointHandler(true)))
+	mux.HandleFunc("DELETE /admin/webhooks/endpoints/{endpointID}", cfg.requireRole(roleAdmin, cfg.deleteWebhookEndpointHandler(true)))
+	mux.HandleFunc("GET /admin/webhooks/endpoints/{endpointID}/deliveries", cfg.requireRole(roleAdmin, cfg.listWebhookDeliveriesHandler(true)))
+	mux.HandleFunc("POST /admin/webhooks/endpoints/{endpointID}/deliveries/{deliveryID}/retry", cfg.requireRole(roleAdmin, cfg.retryWebhookDeliveryHandler(true)))
+	mux.HandleFunc("POST /admin/webhooks/endpoints/{endpointID}/test", cfg.requireRole(roleAdmin, cfg.testWebhookEndpointHandler(true)))
 
 	mux.HandleFunc("GET /api/healthz", healthHandler)
 
 	mux.HandleFunc("GET /api/chirps", cfg.GetChirps)
+	mux.HandleFunc("GET /api/chirps/stream", cfg.streamChirpsHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.GetChirp)
 	mux.HandleFunc("POST /api/chirps", cfg.createChirpHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", cfg.restoreChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", cfg.pinChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", cfg.unpinChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/vote", cfg.votePollHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.rechirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.undoRechirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", cfg.bookmarkChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", cfg.unbookmarkChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", cfg.reportChirpHandler)
 
 	mux.HandleFunc("POST /api/users", cfg.createUserHandler)
 	mux.HandleFunc("PUT  /api/users", cfg.updateUserHandler)
 
+	mux.HandleFunc("POST /api/users/{userID}/block", cfg.blockUserHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", cfg.unblockUserHandler)
+	mux.HandleFunc("POST /api/users/{userID}/mute", cfg.muteUserHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.unmuteUserHandler)
+	mux.HandleFunc("POST /api/users/{userID}/report", cfg.reportUserHandler)
+	mux.HandleFunc("GET /api/users/me/blocks", cfg.listBlocksHandler)
+	mux.HandleFunc("GET /api/users/me/blocks/export", cfg.exportBlocksHandler)
+	mux.HandleFunc("GET /api/users/me/mutes", cfg.listMutesHandler)
+	mux.HandleFunc("GET /api/users/me/mutes/export", cfg.exportMutesHandler)
+
+	mux.HandleFunc("PUT /api/users/me/privacy", cfg.updatePrivacyHandler)
+	mux.HandleFunc("PUT /api/users/me/settings", cfg.updateSettingsHandler)
+	mux.HandleFunc("GET /api/users/me/entitlements", cfg.getEntitlementsHandler)
+	mux.HandleFunc("GET /api/users/me/status", cfg.accountStatusHandler)
+	mux.HandleFunc("GET /api/users/me/security-events", cfg.listSecurityEventsHandler)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.followUserHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.unfollowUserHandler)
+
+	mux.HandleFunc("GET /api/users/{userID}/feed.rss", cfg.userFeedHandler(feedRSS))
+	mux.HandleFunc("GET /api/users/{userID}/feed.atom", cfg.userFeedHandler(feedAtom))
+	mux.HandleFunc("GET /api/users/{userID}/feed.json", cfg.userFeedHandler(feedJSON))
+	mux.HandleFunc("GET /api/hashtags/{tag}/feed.rss", cfg.hashtagFeedHandler(feedRSS))
+	mux.HandleFunc("GET /api/hashtags/{tag}/feed.atom", cfg.hashtagFeedHandler(feedAtom))
+	mux.HandleFunc("GET /api/hashtags/{tag}/feed.json", cfg.hashtagFeedHandler(feedJSON))
+	mux.HandleFunc("GET /api/users/me/follow_requests", cfg.listFollowRequestsHandler)
+	mux.HandleFunc("POST /api/users/me/follow_requests/{userID}/approve", cfg.approveFollowRequestHandler)
+	mux.HandleFunc("POST /api/users/me/follow_requests/{userID}/reject", cfg.rejectFollowRequestHandler)
+
+	mux.HandleFunc("POST /api/conversations", cfg.createConversationHandler)
+	mux.HandleFunc("GET /api/conversations", cfg.listConversationsHandler)
+	mux.HandleFunc("GET /api/conversations/unread", cfg.unreadMessagesHandler)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", cfg.listMessagesHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", cfg.sendMessageHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", cfg.markConversationReadHandler)
+
+	mux.HandleFunc("GET /api/users/me/bookmarks", cfg.listBookmarksHandler)
+	mux.HandleFunc("GET /api/users/me/scheduled", cfg.listScheduledChirpsHandler)
+	mux.HandleFunc("GET /api/users/me/drafts", cfg.listDraftsHandler)
+	mux.HandleFunc("POST /api/users/me/drafts", cfg.createDraftHandler)
+	mux.HandleFunc("PUT /api/users/me/drafts/{draftID}", cfg.updateDraftHandler)
+	mux.HandleFunc("DELETE /api/users/me/drafts/{draftID}", cfg.deleteDraftHandler)
+	mux.HandleFunc("POST /api/users/me/drafts/{draftID}/publish", cfg.publishDraftHandler)
+
+	mux.HandleFunc("POST /api/lists", cfg.createListHandler)
+	mux.HandleFunc("GET /api/lists", cfg.listListsHandler)
+	mux.HandleFunc("GET /api/lists/{listID}", cfg.getListHandler)
+	mux.HandleFunc("PUT /api/lists/{listID}", cfg.updateListHandler)
+	mux.HandleFunc("DELETE /api/lists/{listID}", cfg.deleteListHandler)
+	mux.HandleFunc("POST /api/lists/{listID}/members", cfg.addListMemberHandler)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", cfg.removeListMemberHandler)
+	mux.HandleFunc("GET /api/lists/{listID}/timeline", cfg.listTimelineHandler)
+
 	mux.HandleFunc("POST /api/login", cfg.loginHandler)
 	mux.HandleFunc("POST /api/refresh", cfg.refreshTokenHandler)
 	mux.HandleFunc("POST /api/revoke", cfg.revokeRefreshTokenHandler)
 
-	mux.HandleFunc("POST /api/polka/webhooks", cfg.webhooks)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.paymentWebhookHandler("polka"))
+	mux.HandleFunc("POST /api/payments/fake/webhooks", cfg.paymentWebhookHandler("fake"))
+	mux.HandleFunc("POST /api/payments/fake/checkout", cfg.fakeCheckoutHandler)
+
+	mux.HandleFunc("GET /api/webhooks", cfg.listWebhookEndpointsHandler(false))
+	mux.HandleFunc("POST /api/webhooks", cfg.createWebhookEndpointHandler(false))
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", cfg.deleteWebhookEndpointHandler(false))
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", cfg.listWebhookDeliveriesHandler(false))
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/deliveries/{deliveryID}/retry", cfg.retryWebhookDeliveryHandler(false))
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/test", cfg.testWebhookEndpointHandler(false))
+
+	mux.HandleFunc("GET /api/ws", cfg.wsHandler)
+
+	mux.HandleFunc("GET /api/notifications", cfg.listNotificationsHandler)
+	mux.HandleFunc("GET /api/notifications/unread", cfg.unreadNotificationsHandler)
+	mux.HandleFunc("POST /api/notifications/read", cfg.markAllNotificationsReadHandler)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", cfg.markNotificationReadHandler)
+
+	mux.HandleFunc("GET /.well-known/webfinger", cfg.webfingerHandler)
+	mux.HandleFunc("GET /ap/users/{userID}", cfg.actorHandler)
+	mux.HandleFunc("GET /ap/users/{userID}/outbox", cfg.outboxHandler)
+	mux.HandleFunc("GET /ap/users/{userID}/followers", cfg.followersHandler)
+	mux.HandleFunc("POST /ap/users/{userID}/inbox", cfg.inboxHandler)
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", cfg.noteHandler)
+
+	// Background jobs
+	go runEvery(context.Background(), "publish scheduled chirps", schedulerInterval, cfg.publishDueChirps)
+	go runEvery(context.Background(), "purge deleted chirps", purgeInterval, cfg.purgeDeletedChirps)
+	go runEvery(context.Background(), "prune chirp events", purgeInterval, cfg.pruneChirpEvents)
+	go runEvery(context.Background(), "expire subscriptions", subscriptionExpiryInterval, cfg.expireSubscriptions)
+	go runEvery(context.Background(), "federate chirps", federationInterval, cfg.federateChirpEvents)
+	go runEvery(context.Background(), "deliver activities", deliveryInterval, cfg.deliverActivities)
+	go runEvery(context.Background(), "queue chirp webhooks", webhookQueueInterval, cfg.queueChirpWebhooks)
+	go runEvery(context.Background(), "deliver webhooks", webhookDeliveryInterval, cfg.deliverWebhooks)
+	go cfg.chirpHub.run(context.Background(), dbURL)
+	go cfg.notificationHub.run(context.Background(), dbURL)
 
 	server := &http.Server{
 		Addr:    ":" + port,
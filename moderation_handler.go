@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+// setChirpSensitiveHandler lets a moderator force (or lift) the sensitive
+// flag on a chirp they reviewed, whatever its author chose.
+func (cfg *apiConfig) setChirpSensitiveHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Sensitive bool `json:"sensitive"`
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	chirp, err := cfg.db.SetChirpSensitive(r.Context(), database.SetChirpSensitiveParams{
+		ID:        chirpID,
+		Sensitive: params.Sensitive,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to update chirp", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newChirp(chirp))
+}
+
+// setChirpHiddenHandler hides a chirp from everyone but its author and
+// moderators, or makes it visible again.
+func (cfg *apiConfig) setChirpHiddenHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Hidden bool `json:"hidden"`
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	chirp, err := cfg.db.SetChirpHidden(r.Context(), database.SetChirpHiddenParams{
+		Hidden: params.Hidden,
+		ID:     chirpID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to update chirp", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newChirp(chirp))
+}
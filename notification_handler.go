@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Notification types.
+const (
+	notificationFollow         = "follow"
+	notificationFollowRequest  = "follow_request"
+	notificationFollowAccepted = "follow_accepted"
+	notificationRechirp        = "rechirp"
+	notificationQuote          = "quote"
+	notificationChirpyRed      = "chirpy_red"
+	notificationWarning        = "warning"
+	notificationChirpHidden    = "chirp_hidden"
+	notificationSuspended      = "suspended"
+)
+
+var notificationTypes = map[string]bool{
+	notificationFollow:         true,
+	notificationFollowRequest:  true,
+	notificationFollowAccepted: true,
+	notificationRechirp:        true,
+	notificationQuote:          true,
+	notificationChirpyRed:      true,
+	notificationWarning:        true,
+	notificationChirpHidden:    true,
+	notificationSuspended:      true,
+}
+
+const (
+	defaultNotificationsPage = 20
+	maxNotificationsPage     = 100
+)
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+type NotificationGroup struct {
+	Type          string         `json:"type"`
+	Unread        int64          `json:"unread"`
+	Notifications []Notification `json:"notifications"`
+}
+
+func newNotification(n database.Notification) Notification {
+	notification := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Type:      n.Type,
+	}
+	if n.ActorID.Valid {
+		notification.ActorID = &n.ActorID.UUID
+	}
+	if n.ChirpID.Valid {
+		notification.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		notification.ReadAt = &n.ReadAt.Time
+	}
+	return notification
+}
+
+// notify records a notification for userID. actorID and chirpID may be
+// uuid.Nil. Notifications are a side effect of the action that triggered
+// them, so failures are logged instead of failing that action.
+func (cfg *apiConfig) notify(ctx context.Context, userID uuid.UUID, kind string, actorID, chirpID uuid.UUID) {
+	_, err := cfg.db.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  userID,
+		Type:    kind,
+		ActorID: uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: chirpID != uuid.Nil},
+	})
+	if err != nil {
+		log.Printf("cannot record %s notification for %s: %v", kind, userID, err)
+	}
+}
+
+// parseNotificationType reads the optional type query parameter; "" means
+// every type.
+func parseNotificationType(w http.ResponseWriter, r *http.Request) (string, bool) {
+	kind := r.URL.Query().Get("type")
+	if kind != "" && !notificationTypes[kind] {
+		jsonError(w, http.StatusBadRequest, "unknown notification type", nil)
+		return "", false
+	}
+	return kind, true
+}
+
+// listNotificationsHandler returns a page of notifications, newest first,
+// grouped by type. The next page is requested with before=next_before.
+func (cfg *apiConfig) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Groups     []NotificationGroup `json:"groups"`
+		NextBefore *uuid.UUID          `json:"next_before"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	kind, ok := parseNotificationType(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultNotificationsPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxNotificationsPage {
+			jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxNotificationsPage), err)
+			return
+		}
+		limit = n
+	}
+
+	before := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid before (must be UUID)", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbNotifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:     userID,
+		Type:       kind,
+		Before:     before,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list notifications", err)
+		return
+	}
+
+	unread, err := cfg.unreadNotificationCounts(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to count notifications", err)
+		return
+	}
+
+	// Groups keep the order in which their newest notification appears.
+	resp := response{Groups: []NotificationGroup{}}
+	index := map[string]int{}
+	for _, n := range dbNotifications {
+		i, ok := index[n.Type]
+		if !ok {
+			i = len(resp.Groups)
+			index[n.Type] = i
+			resp.Groups = append(resp.Groups, NotificationGroup{
+				Type:          n.Type,
+				Unread:        unread[n.Type],
+				Notifications: []Notification{},
+			})
+		}
+		resp.Groups[i].Notifications = append(resp.Groups[i].Notifications, newNotification(n))
+	}
+	if len(dbNotifications) == limit {
+		last := dbNotifications[len(dbNotifications)-1].ID
+		resp.NextBefore = &last
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) unreadNotificationCounts(ctx context.Context, userID uuid.UUID) (map[string]int64, error) {
+	rows, err := cfg.db.GetUnreadNotificationCounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+func (cfg *apiConfig) unreadNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Unread int64            `json:"unread"`
+		ByType map[string]int64 `json:"by_type"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	counts, err := cfg.unreadNotificationCounts(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to count notifications", err)
+		return
+	}
+
+	resp := response{ByType: counts}
+	for _, count := range counts {
+		resp.Unread += count
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	notificationID, ok := parseUUIDPathValue(w, r, "notificationID")
+	if !ok {
+		return
+	}
+
+	marked, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to mark notification as read", err)
+		return
+	}
+	if marked == 0 {
+		jsonError(w, http.StatusNotFound, "notification not found", sql.ErrNoRows)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// markAllNotificationsReadHandler marks every notification as read, or only
+// those of the type given in the query string.
+func (cfg *apiConfig) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	kind, ok := parseNotificationType(w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.db.MarkAllNotificationsRead(r.Context(), database.MarkAllNotificationsReadParams{
+		UserID: userID,
+		Type:   kind,
+	}); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to mark notifications as read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
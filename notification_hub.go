@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	notificationsChannel     = "notifications"
+	notificationStreamBuffer = 16
+)
+
+// notificationHub hands the notifications announced by Postgres to the
+// WebSocket sessions of their recipient on this instance. Notifications
+// announced while the listener was disconnected aren't replayed: they are
+// still listed by GET /api/notifications.
+type notificationHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan uuid.UUID]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{
+		subscribers: map[uuid.UUID]map[chan uuid.UUID]struct{}{},
+	}
+}
+
+// subscribe returns a channel receiving the ids of userID's new
+// notifications.
+func (h *notificationHub) subscribe(userID uuid.UUID) chan uuid.UUID {
+	ch := make(chan uuid.UUID, notificationStreamBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = map[chan uuid.UUID]struct{}{}
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	return ch
+}
+
+func (h *notificationHub) unsubscribe(userID uuid.UUID, ch chan uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[userID][ch]; ok {
+		h.drop(userID, ch)
+	}
+}
+
+func (h *notificationHub) drop(userID uuid.UUID, ch chan uuid.UUID) {
+	delete(h.subscribers[userID], ch)
+	if len(h.subscribers[userID]) == 0 {
+		delete(h.subscribers, userID)
+	}
+	close(ch)
+}
+
+// broadcast never blocks: a subscriber whose buffer is full is dropped.
+func (h *notificationHub) broadcast(userID, notificationID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- notificationID:
+		default:
+			h.drop(userID, ch)
+		}
+	}
+}
+
+// run listens for notifications until ctx is done.
+func (h *notificationHub) run(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("notifications listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notificationsChannel); err != nil {
+		log.Printf("cannot listen to %s: %v", notificationsChannel, err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established.
+			if n == nil {
+				continue
+			}
+			var payload struct {
+				ID     uuid.UUID `json:"id"`
+				UserID uuid.UUID `json:"user_id"`
+			}
+			if err := json.Unmarshal([]byte(n.Extra), &payload); err != nil {
+				log.Printf("invalid notification announcement %q: %v", n.Extra, err)
+				continue
+			}
+			h.broadcast(payload.UserID, payload.ID)
+		case <-time.After(time.Minute):
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/safehttp"
+	"github.com/AymaneIsmail/chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// Events sent to webhook endpoints.
+const (
+	webhookChirpCreated = "chirp.created"
+	webhookChirpDeleted = "chirp.deleted"
+	webhookUserFollowed = "user.followed"
+	// Only sent on demand, to test an endpoint.
+	webhookPing = "ping"
+)
+
+var webhookEventTypes = map[string]bool{
+	webhookChirpCreated: true,
+	webhookChirpDeleted: true,
+	webhookUserFollowed: true,
+}
+
+// Webhook delivery statuses.
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryDead      = "dead"
+)
+
+const (
+	webhookQueueInterval = 10 * time.Second
+	webhookQueueBatch    = 100
+
+	webhookDeliveryInterval = 10 * time.Second
+	webhookDeliveryBatch    = 50
+	maxWebhookAttempts      = 10
+	webhookBaseBackoff      = 30 * time.Second
+	maxWebhookBackoff       = 12 * time.Hour
+)
+
+// OutboundEvent is the body of every webhook request.
+type OutboundEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+func newOutboundEvent(kind string, data any) ([]byte, error) {
+	return json.Marshal(OutboundEvent{
+		ID:        uuid.New(),
+		Type:      kind,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+type chirpDeletedData struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type userFollowedData struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FollowedID uuid.UUID `json:"followed_id"`
+}
+
+func enqueueWebhookEvent(ctx context.Context, q *database.Queries, userID uuid.UUID, kind string, data any) error {
+	payload, err := newOutboundEvent(kind, data)
+	if err != nil {
+		return err
+	}
+	_, err = q.EnqueueWebhookEvent(ctx, database.EnqueueWebhookEventParams{
+		Event:   kind,
+		Payload: string(payload),
+		UserID:  userID,
+	})
+	return err
+}
+
+// emitWebhook queues an event about userID for the endpoints subscribed to
+// it. Like notifications, webhooks are a side effect of the action that
+// triggered them, so failures are logged instead of failing that action.
+func (cfg *apiConfig) emitWebhook(ctx context.Context, userID uuid.UUID, kind string, data any) {
+	if err := enqueueWebhookEvent(ctx, cfg.db, userID, kind, data); err != nil {
+		log.Printf("cannot queue %s webhook for %s: %v", kind, userID, err)
+	}
+}
+
+// queueChirpWebhooks turns new chirp events into webhook deliveries. The
+// cursor row is locked for the whole batch, so concurrent instances never
+// queue an event twice.
+func (cfg *apiConfig) queueChirpWebhooks(ctx context.Context) error {
+	for {
+		var handled int
+		err := cfg.withTx(ctx, func(q *database.Queries) error {
+			cursor, err := q.LockWebhookCursor(ctx)
+			if err != nil {
+				return err
+			}
+			events, err := q.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+				AfterID:   cursor,
+				MaxEvents: webhookQueueBatch,
+			})
+			if err != nil {
+				return err
+			}
+			handled = len(events)
+			if handled == 0 {
+				return nil
+			}
+
+			for _, event := range events {
+				if err := queueChirpWebhook(ctx, q, event); err != nil {
+					return err
+				}
+			}
+			return q.SetWebhookCursor(ctx, events[len(events)-1].ID)
+		})
+		if err != nil || handled < webhookQueueBatch {
+			return err
+		}
+	}
+}
+
+func queueChirpWebhook(ctx context.Context, q *database.Queries, event database.ChirpEvent) error {
+	switch event.Kind {
+	case "created":
+		restricted, err := isRestricted(ctx, q, event.UserID)
+		if err != nil || restricted {
+			return err
+		}
+		chirp, err := q.GetChirp(ctx, event.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return enqueueWebhookEvent(ctx, q, event.UserID, webhookChirpCreated, newChirp(chirp))
+
+	case "deleted":
+		return enqueueWebhookEvent(ctx, q, event.UserID, webhookChirpDeleted, chirpDeletedData{
+			ChirpID: event.ChirpID,
+			UserID:  event.UserID,
+		})
+	}
+	return nil
+}
+
+// deliverWebhooks sends queued deliveries. Failed deliveries are retried
+// with an exponential backoff, and declared dead once out of attempts.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context) error {
+	deliveries, err := cfg.db.ClaimDueWebhookDeliveries(ctx, webhookDeliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		sendErr := cfg.sendWebhook(ctx, d.ID, d.Url, d.Secret, d.Event, d.Payload)
+
+		switch {
+		case sendErr == nil:
+			err = cfg.db.MarkWebhookDelivered(ctx, d.ID)
+		case d.Attempts+1 >= maxWebhookAttempts:
+			log.Printf("webhook delivery %s to %s is dead: %v", d.ID, d.Url, sendErr)
+			err = cfg.db.KillWebhookDelivery(ctx, d.ID)
+		default:
+			err = cfg.db.RetryWebhookDelivery(ctx, database.RetryWebhookDeliveryParams{
+				ID:            d.ID,
+				NextAttemptAt: time.Now().UTC().Add(backoff(webhookBaseBackoff, maxWebhookBackoff, d.Attempts)),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendWebhook makes one attempt at a delivery and logs it.
+func (cfg *apiConfig) sendWebhook(ctx context.Context, deliveryID uuid.UUID, url, secret, event, payload string) error {
+	result, sendErr := cfg.webhookClient.Send(ctx, url, secret, deliveryID.String(), event, []byte(payload))
+
+	errMsg := ""
+	if sendErr != nil {
+		errMsg = webhookErrorMessage(sendErr)
+		log.Printf("webhook delivery %s failed: %v", deliveryID, sendErr)
+	}
+	// How long a failed connection took says something about the network
+	// behind the endpoint, so it's only kept when the endpoint answered.
+	var durationMs int32
+	if result.StatusCode != 0 {
+		durationMs = int32(result.Duration.Milliseconds())
+	}
+	if err := cfg.db.RecordWebhookAttempt(ctx, database.RecordWebhookAttemptParams{
+		DeliveryID: deliveryID,
+		StatusCode: int32(result.StatusCode),
+		Error:      errMsg,
+		DurationMs: durationMs,
+	}); err != nil {
+		log.Printf("cannot log webhook attempt for %s: %v", deliveryID, err)
+	}
+	return sendErr
+}
+
+// webhookErrorMessage describes a failed delivery to the endpoint's owner.
+// Transport errors are reduced to a category: their text would tell the
+// owner how the server sees the network behind the URL they chose.
+func webhookErrorMessage(err error) string {
+	var statusErr *webhook.StatusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Error()
+	case errors.Is(err, safehttp.ErrAddress), errors.Is(err, safehttp.ErrScheme):
+		return "endpoint address is not allowed"
+	case errors.Is(err, safehttp.ErrTooManyRedirects):
+		return "too many redirects"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "couldn't reach endpoint"
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/payments"
+)
+
+// fakeCheckoutHandler simulates the fake provider: it signs the event a
+// real provider would send for the caller's subscription and feeds it to
+// the webhook pipeline, as if it had been received.
+func (cfg *apiConfig) fakeCheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		// Defaults to a new subscription.
+		Event     string     `json:"event"`
+		PeriodEnd *time.Time `json:"period_end"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	fake, ok := cfg.paymentProviders["fake"].(*payments.Fake)
+	if !ok {
+		jsonError(w, http.StatusNotFound, "payment provider not enabled", nil)
+		return
+	}
+
+	var params parameters
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+			return
+		}
+	}
+	if params.Event == "" {
+		params.Event = payments.SubscriptionStarted
+	}
+	var periodEnd time.Time
+	if params.PeriodEnd != nil {
+		periodEnd = *params.PeriodEnd
+	}
+
+	body, header, err := fake.Checkout(userID, params.Event, periodEnd)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to build event", err)
+		return
+	}
+
+	webhook, err := http.NewRequestWithContext(r.Context(), http.MethodPost, "/api/payments/fake/webhooks", bytes.NewReader(body))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to build webhook", err)
+		return
+	}
+	webhook.Header = header
+
+	event, ok := cfg.receivePaymentWebhook(w, webhook, fake, body)
+	if !ok {
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newWebhookEvent(event))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) pinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	// Deleted chirps are answered like missing ones.
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		jsonError(w, http.StatusForbidden, "you can only pin your own chirps", nil)
+		return
+	}
+	if chirp.RechirpOfID.Valid || chirp.PublishAt.Valid {
+		jsonError(w, http.StatusBadRequest, "rechirps and scheduled chirps can't be pinned", nil)
+		return
+	}
+
+	// Pinning replaces any previously pinned chirp.
+	user, err := cfg.db.SetPinnedChirp(r.Context(), database.SetPinnedChirpParams{
+		ID:            userID,
+		PinnedChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to pin chirp", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newUser(user))
+}
+
+func (cfg *apiConfig) unpinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	unpinned, err := cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		ID:            userID,
+		PinnedChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to unpin chirp", err)
+		return
+	}
+	if unpinned == 0 {
+		jsonError(w, http.StatusNotFound, "chirp is not pinned", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// movePinnedFirst puts the author's pinned chirp at the top of an
+// author-filtered listing, whatever the sort order.
+func movePinnedFirst(chirps []Chirp, pinnedID uuid.UUID) []Chirp {
+	for i, c := range chirps {
+		if c.ID != pinnedID {
+			continue
+		}
+		c.Pinned = true
+		out := make([]Chirp, 0, len(chirps))
+		out = append(out, c)
+		out = append(out, chirps[:i]...)
+		return append(out, chirps[i+1:]...)
+	}
+	return chirps
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+type Poll struct {
+	ID       uuid.UUID    `json:"id"`
+	ClosesAt time.Time    `json:"closes_at"`
+	Closed   bool         `json:"closed"`
+	Options  []PollOption `json:"options"`
+
+	// Results are only revealed once the reader voted or the poll closed.
+	ResultsVisible bool       `json:"results_visible"`
+	TotalVotes     *int64     `json:"total_votes,omitempty"`
+	VotedOptionID  *uuid.UUID `json:"voted_option_id,omitempty"`
+}
+
+type PollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+type pollParameters struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// validate trims and filters the options like a chirp body and checks the
+// poll's shape and closing time. opensAt is when the chirp is published:
+// a scheduled chirp's poll runs from its publication.
+func (p *pollParameters) validate(opensAt time.Time) error {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return errors.New("a poll needs between 2 and 4 options")
+	}
+	for i, option := range p.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("poll options can't be empty")
+		}
+		if len(option) > maxPollOptionLength {
+			return errors.New("poll option is too long")
+		}
+		p.Options[i] = strings.Join(validateWords(strings.Split(option, " ")), " ")
+	}
+
+	if !p.ClosesAt.After(opensAt) {
+		return errors.New("closes_at must be after the chirp is published")
+	}
+	if p.ClosesAt.After(opensAt.Add(maxPollDuration)) {
+		return errors.New("polls can stay open for at most 7 days")
+	}
+	p.ClosesAt = p.ClosesAt.UTC()
+	return nil
+}
+
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, params pollParameters) error {
+	poll, err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: params.ClosesAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.CreatePollOptions(ctx, database.CreatePollOptionsParams{
+		PollID: poll.ID,
+		Texts:  params.Options,
+	})
+}
+
+func (cfg *apiConfig) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return
+	}
+
+	poll, err := cfg.db.GetPollByChirpID(r.Context(), chirp.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "this chirp has no poll", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load poll", err)
+		return
+	}
+
+	if !time.Now().Before(poll.ClosesAt) {
+		jsonError(w, http.StatusConflict, "poll is closed", nil)
+		return
+	}
+
+	option, err := cfg.db.GetPollOption(r.Context(), params.OptionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusInternalServerError, "failed to load poll option", err)
+		return
+	}
+	if err != nil || option.PollID != poll.ID {
+		jsonError(w, http.StatusBadRequest, "invalid option_id for this poll", err)
+		return
+	}
+
+	voted, err := cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		PollID:   poll.ID,
+		UserID:   userID,
+		OptionID: option.ID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to record vote", err)
+		return
+	}
+	if voted == 0 {
+		jsonError(w, http.StatusConflict, "you already voted in this poll", nil)
+		return
+	}
+
+	polls, err := cfg.loadPolls(r.Context(), viewer, []uuid.UUID{chirp.ID})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to load poll", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, polls[chirp.ID])
+}
+
+// loadPolls returns the polls attached to the given chirps, keyed by chirp
+// ID, with results filled in only where the viewer may see them.
+func (cfg *apiConfig) loadPolls(ctx context.Context, viewer *chirpViewer, chirpIDs []uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	polls := map[uuid.UUID]*Poll{}
+	if len(chirpIDs) == 0 {
+		return polls, nil
+	}
+
+	dbPolls, err := cfg.db.GetPollsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbPolls) == 0 {
+		return polls, nil
+	}
+
+	pollIDs := make([]uuid.UUID, 0, len(dbPolls))
+	for _, p := range dbPolls {
+		pollIDs = append(pollIDs, p.ID)
+	}
+
+	votes := map[uuid.UUID]uuid.UUID{}
+	if viewer.userID != uuid.Nil {
+		rows, err := cfg.db.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:  viewer.userID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			votes[row.PollID] = row.OptionID
+		}
+	}
+
+	results, err := cfg.db.GetPollOptionResults(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byPollID := map[uuid.UUID]*Poll{}
+	now := time.Now()
+	for _, p := range dbPolls {
+		poll := &Poll{
+			ID:       p.ID,
+			ClosesAt: p.ClosesAt,
+			Closed:   !now.Before(p.ClosesAt),
+			Options:  []PollOption{},
+		}
+		if optionID, ok := votes[p.ID]; ok {
+			poll.VotedOptionID = &optionID
+		}
+		poll.ResultsVisible = poll.Closed || poll.VotedOptionID != nil
+		if poll.ResultsVisible {
+			poll.TotalVotes = new(int64)
+		}
+		polls[p.ChirpID] = poll
+		byPollID[p.ID] = poll
+	}
+
+	for _, row := range results {
+		poll := byPollID[row.PollID]
+		option := PollOption{ID: row.ID, Text: row.Text}
+		if poll.ResultsVisible {
+			count := row.Votes
+			option.Votes = &count
+			*poll.TotalVotes += count
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	return polls, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+
+	original, ok := cfg.shareableChirp(w, r, viewer, chirpID)
+	if !ok {
+		return
+	}
+	originalID := uuid.NullUUID{UUID: original.ID, Valid: true}
+
+	rechirp, err := cfg.db.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:      userID,
+		RechirpOfID: originalID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Rechirping twice is a no-op that returns the existing rechirp.
+		existing, err := cfg.db.GetRechirp(r.Context(), database.GetRechirpParams{
+			UserID:      userID,
+			RechirpOfID: originalID,
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load rechirp", err)
+			return
+		}
+		cfg.respondWithChirp(w, r, http.StatusOK, viewer, existing)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to rechirp", err)
+		return
+	}
+	cfg.notify(r.Context(), original.UserID, notificationRechirp, userID, original.ID)
+
+	cfg.respondWithChirp(w, r, http.StatusCreated, viewer, rechirp)
+}
+
+func (cfg *apiConfig) undoRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	deleted, err := cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to undo rechirp", err)
+		return
+	}
+	if deleted == 0 {
+		jsonError(w, http.StatusNotFound, "rechirp not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shareableChirp resolves the chirp being rechirped or quoted. Sharing a
+// rechirp shares its original, and users can't share chirps they can't see
+// or whose author blocked them (or whom they blocked).
+func (cfg *apiConfig) shareableChirp(w http.ResponseWriter, r *http.Request, viewer *chirpViewer, chirpID uuid.UUID) (database.Chirp, bool) {
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err == nil && chirp.RechirpOfID.Valid {
+		chirp, err = cfg.getVisibleChirp(r.Context(), viewer, chirp.RechirpOfID.UUID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "chirp not found", err)
+			return database.Chirp{}, false
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load chirp", err)
+		return database.Chirp{}, false
+	}
+
+	blocked, err := cfg.blockedBetween(r.Context(), viewer.userID, chirp.UserID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to check blocks", err)
+		return database.Chirp{}, false
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you cannot share this chirp", nil)
+		return database.Chirp{}, false
+	}
+
+	return chirp, true
+}
@@ -40,13 +40,18 @@ func (cfg *apiConfig) refreshTokenHandler(w http.ResponseWriter, r *http.Request
 		jsonError(w, http.StatusUnauthorized, "No user found", err)
 		return
 	}
+	if !cfg.checkNotSuspended(w, r, user.ID) {
+		return
+	}
 
-	tokenStr, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+	tokenStr, err := auth.MakeJWT(user.ID, user.Role, cfg.JWTSecret, time.Hour)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "Couldn't generate JWT", err)
 		return
 	}
 
+	cfg.audit(r, auditEvent{Action: auditTokenRefreshed, ActorID: user.ID, TargetID: user.ID})
+
 	jsonResponse(w, http.StatusOK, RefreshTokenResponse{Token: tokenStr})
 }
 
@@ -58,7 +63,8 @@ func (cfg *apiConfig) revokeRefreshTokenHandler(w http.ResponseWriter, r *http.R
 	}
 
 	// Optionnel : vérifier l’existence du token pour renvoyer 401 s’il n’existe pas
-	if _, err := cfg.db.GetRefreshTokenByToken(r.Context(), bearerToken); err != nil {
+	rt, err := cfg.db.GetRefreshTokenByToken(r.Context(), bearerToken)
+	if err != nil {
 		jsonError(w, http.StatusUnauthorized, "Refresh token not found", err)
 		return
 	}
@@ -68,6 +74,7 @@ func (cfg *apiConfig) revokeRefreshTokenHandler(w http.ResponseWriter, r *http.R
 		jsonError(w, http.StatusInternalServerError, "Failed to revoke token", err)
 		return
 	}
+	cfg.audit(r, auditEvent{Action: auditTokenRevoked, ActorID: rt.UserID, TargetID: rt.UserID})
 
 	// 204 No Content
 	w.WriteHeader(http.StatusNoContent)
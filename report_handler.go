@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var reportReasons = map[string]bool{
+	"spam":          true,
+	"harassment":    true,
+	"hate":          true,
+	"violence":      true,
+	"sexual":        true,
+	"self_harm":     true,
+	"impersonation": true,
+	"other":         true,
+}
+
+// Report statuses.
+const (
+	reportOpen     = "open"
+	reportResolved = "resolved"
+)
+
+// Actions a moderator resolves a report with.
+const (
+	reportDismiss = "dismiss"
+	reportHide    = "hide"
+	reportSuspend = "suspend"
+	reportWarn    = "warn"
+)
+
+const (
+	defaultReportsPage = 50
+	maxReportsPage     = 200
+
+	maxReportDetailsLength = 1000
+)
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	Action     string     `json:"action"`
+	Note       string     `json:"note"`
+	ResolvedBy *uuid.UUID `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+func newReport(r database.Report) Report {
+	report := Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		ReporterID: r.ReporterID,
+		UserID:     r.UserID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+		Action:     r.Action,
+		Note:       r.Note,
+	}
+	if r.ChirpID.Valid {
+		report.ChirpID = &r.ChirpID.UUID
+	}
+	if r.ResolvedBy.Valid {
+		report.ResolvedBy = &r.ResolvedBy.UUID
+	}
+	if r.ResolvedAt.Valid {
+		report.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return report
+}
+
+type reportParameters struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func decodeReportParameters(w http.ResponseWriter, r *http.Request) (reportParameters, bool) {
+	var params reportParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return reportParameters{}, false
+	}
+	if !reportReasons[params.Reason] {
+		jsonError(w, http.StatusBadRequest, "unknown reason", nil)
+		return reportParameters{}, false
+	}
+	if len(params.Details) > maxReportDetailsLength {
+		jsonError(w, http.StatusBadRequest, "details are too long", nil)
+		return reportParameters{}, false
+	}
+	return params, true
+}
+
+func (cfg *apiConfig) createReport(w http.ResponseWriter, r *http.Request, arg database.CreateReportParams) {
+	report, err := cfg.db.CreateReport(r.Context(), arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusConflict, "already reported", err)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to create report", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newReport(report))
+}
+
+// reportChirpHandler reports a chirp the caller can see.
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, ok := parseUUIDPathValue(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	params, ok := decodeReportParameters(w, r)
+	if !ok {
+		return
+	}
+
+	viewer, err := cfg.loadChirpViewer(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot load viewer", err)
+		return
+	}
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Cannot get chirp", err)
+		return
+	}
+	if chirp.UserID == userID {
+		jsonError(w, http.StatusBadRequest, "you can't report your own chirp", nil)
+		return
+	}
+
+	cfg.createReport(w, r, database.CreateReportParams{
+		ReporterID: userID,
+		UserID:     chirp.UserID,
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+}
+
+// reportUserHandler reports a user as a whole.
+func (cfg *apiConfig) reportUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	reportedID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+	if reportedID == userID {
+		jsonError(w, http.StatusBadRequest, "you can't report yourself", nil)
+		return
+	}
+
+	params, ok := decodeReportParameters(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := cfg.db.GetUserById(r.Context(), reportedID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to get user", err)
+		return
+	}
+
+	cfg.createReport(w, r, database.CreateReportParams{
+		ReporterID: userID,
+		UserID:     reportedID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+}
+
+// listReportsHandler returns reports, newest first, optionally filtered by
+// status and reason. The next page is requested with before=next_before.
+func (cfg *apiConfig) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Reports    []Report   `json:"reports"`
+		NextBefore *uuid.UUID `json:"next_before"`
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != reportOpen && status != reportResolved {
+		jsonError(w, http.StatusBadRequest, "status must be open or resolved", nil)
+		return
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason != "" && !reportReasons[reason] {
+		jsonError(w, http.StatusBadRequest, "unknown reason", nil)
+		return
+	}
+
+	limit := defaultReportsPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxReportsPage {
+			jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxReportsPage), err)
+			return
+		}
+		limit = n
+	}
+
+	before := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid before (must be UUID)", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbReports, err := cfg.db.GetReports(r.Context(), database.GetReportsParams{
+		Status:     status,
+		Reason:     reason,
+		Before:     before,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list reports", err)
+		return
+	}
+
+	resp := response{Reports: make([]Report, 0, len(dbReports))}
+	for _, report := range dbReports {
+		resp.Reports = append(resp.Reports, newReport(report))
+	}
+	if len(dbReports) == limit {
+		last := dbReports[len(dbReports)-1].ID
+		resp.NextBefore = &last
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// resolveReportHandler closes an open report with an action: dismiss it,
+// hide the reported chirp, suspend the reported user for a duration, or
+// warn them. The reported user is notified of anything but a dismissal.
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+		// How long to suspend for, e.g. "72h".
+		Duration string `json:"duration"`
+	}
+
+	reportID, ok := parseUUIDPathValue(w, r, "reportID")
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	var duration time.Duration
+	switch params.Action {
+	case reportDismiss, reportHide, reportWarn:
+	case reportSuspend:
+		d, err := time.ParseDuration(params.Duration)
+		if err != nil || d <= 0 {
+			jsonError(w, http.StatusBadRequest, "duration must be a positive duration (e.g. 72h)", err)
+			return
+		}
+		duration = d
+	default:
+		jsonError(w, http.StatusBadRequest, "action must be dismiss, hide, suspend or warn", nil)
+		return
+	}
+
+	report, err := cfg.db.GetReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusNotFound, "report not found", err)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to get report", err)
+		return
+	}
+	if params.Action == reportHide && !report.ChirpID.Valid {
+		jsonError(w, http.StatusBadRequest, "only chirp reports can hide a chirp", nil)
+		return
+	}
+
+	moderatorID := staffID(r)
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		report, err = q.ResolveReport(r.Context(), database.ResolveReportParams{
+			Action:     params.Action,
+			Note:       params.Note,
+			ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+			ID:         reportID,
+		})
+		if err != nil {
+			return err
+		}
+		return applyReportAction(r.Context(), q, report, duration)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusConflict, "report already resolved", err)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to resolve report", err)
+		return
+	}
+
+	// Moderators act anonymously, which also keeps the notification from
+	// being dropped when the reported user blocked them.
+	switch report.Action {
+	case reportHide:
+		cfg.notify(r.Context(), report.UserID, notificationChirpHidden, uuid.Nil, report.ChirpID.UUID)
+	case reportSuspend:
+		cfg.notify(r.Context(), report.UserID, notificationSuspended, uuid.Nil, uuid.Nil)
+	case reportWarn:
+		cfg.notify(r.Context(), report.UserID, notificationWarning, uuid.Nil, report.ChirpID.UUID)
+	}
+
+	jsonResponse(w, http.StatusOK, newReport(report))
+}
+
+func applyReportAction(ctx context.Context, q *database.Queries, report database.Report, duration time.Duration) error {
+	switch report.Action {
+	case reportHide:
+		_, err := q.SetChirpHidden(ctx, database.SetChirpHiddenParams{
+			Hidden: true,
+			ID:     report.ChirpID.UUID,
+		})
+		return err
+	case reportSuspend:
+		reason := report.Note
+		if reason == "" {
+			reason = report.Reason
+		}
+		_, err := q.CreateSuspension(ctx, database.CreateSuspensionParams{
+			UserID:    report.UserID,
+			Kind:      suspensionSuspended,
+			Reason:    reason,
+			EndsAt:    time.Now().UTC().Add(duration),
+			CreatedBy: report.ResolvedBy,
+			ReportID:  uuid.NullUUID{UUID: report.ID, Valid: true},
+		})
+		return err
+	}
+	return nil
+}
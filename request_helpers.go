@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// authenticateUser validates the Bearer JWT of the request and returns the
+// caller's user ID. On failure it writes a 401, also for tokens of users
+// that were deleted since, or a 403 for suspended users, and returns false.
+func (cfg *apiConfig) authenticateUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := cfg.authenticateToken(w, r)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	if !cfg.checkUserExists(w, r, userID) || !cfg.checkNotSuspended(w, r, userID) {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// checkUserExists answers 401 and returns false when the user a valid
+// token was issued to has been deleted since.
+func (cfg *apiConfig) checkUserExists(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	_, err := cfg.db.GetUserById(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusUnauthorized, "user not found", err)
+		return false
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to get user", err)
+		return false
+	}
+	return true
+}
+
+// authenticateToken is authenticateUser without the suspension check, for
+// the few endpoints suspended users can still use.
+func (cfg *apiConfig) authenticateToken(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		jsonError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return uuid.Nil, false
+	}
+
+	userID, err := auth.ValidateJWT(bearerToken, cfg.JWTSecret)
+	if err != nil {
+		jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// optionalUser is used by public read endpoints: anonymous callers get
+// uuid.Nil, while a present but invalid token is still rejected.
+func (cfg *apiConfig) optionalUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil, true
+	}
+	return cfg.authenticateUser(w, r)
+}
+
+func parseUUIDPathValue(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	raw := r.PathValue(name)
+	if raw == "" {
+		jsonError(w, http.StatusBadRequest, "no "+name+" provided", nil)
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "invalid "+name+" (must be UUID)", err)
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
@@ -2,6 +2,16 @@ package main
 
 import "net/http"
 
+// resetHandler guards the reset. It deletes every user, admins included,
+// so on the dev platform, where it is run between test runs, it is left
+// open; anywhere else it takes an admin, and is refused anyway.
+func (cfg *apiConfig) resetHandler() http.HandlerFunc {
+	if cfg.Platform == "dev" {
+		return cfg.resetUserHandler
+	}
+	return cfg.requireRole(roleAdmin, cfg.resetUserHandler)
+}
+
 func (cfg *apiConfig) resetUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	if cfg.Platform != "dev" {
@@ -16,6 +26,8 @@ func (cfg *apiConfig) resetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg.audit(r, auditEvent{Action: auditAdminReset, ActorID: staffID(r)})
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits reset to 0 and database reset to initial state."))
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+// execOnlyDB accepts every statement run with ExecContext, and fails the
+// queries that would need rows.
+type execOnlyDB struct {
+	execs []string
+}
+
+func (db *execOnlyDB) ExecContext(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+	db.execs = append(db.execs, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (db *execOnlyDB) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (db *execOnlyDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (db *execOnlyDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func TestResetTwiceOnDev(t *testing.T) {
+	db := &execOnlyDB{}
+	cfg := &apiConfig{db: database.New(db), Platform: "dev", JWTSecret: "secret"}
+	handler := cfg.resetHandler()
+
+	// The first reset deletes every admin; the second must still work.
+	for i := range 2 {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/admin/reset", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("reset %d: status = %d, want 200: %s", i+1, rec.Code, rec.Body)
+		}
+	}
+
+	resets := 0
+	for _, query := range db.execs {
+		if strings.Contains(query, "name: Reset ") {
+			resets++
+		}
+	}
+	if resets != 2 {
+		t.Errorf("ran %d resets, want 2", resets)
+	}
+}
+
+func TestResetNeedsAdminOutsideDev(t *testing.T) {
+	db := &execOnlyDB{}
+	cfg := &apiConfig{db: database.New(db), Platform: "prod", JWTSecret: "secret"}
+
+	rec := httptest.NewRecorder()
+	cfg.resetHandler()(rec, httptest.NewRequest(http.MethodPost, "/admin/reset", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+	if len(db.execs) != 0 {
+		t.Errorf("ran %d statements, want none", len(db.execs))
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRoleChangesPage = 50
+	maxRoleChangesPage     = 200
+)
+
+type RoleChange struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	OldRole   string     `json:"old_role"`
+	NewRole   string     `json:"new_role"`
+}
+
+func newRoleChange(c database.RoleChange) RoleChange {
+	change := RoleChange{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UserID:    c.UserID,
+		OldRole:   c.OldRole,
+		NewRole:   c.NewRole,
+	}
+	if c.ActorID.Valid {
+		change.ActorID = &c.ActorID.UUID
+	}
+	return change
+}
+
+// grantRoleHandler sets the role of {userID}.
+func (cfg *apiConfig) grantRoleHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if _, ok := roleRanks[params.Role]; !ok {
+		jsonError(w, http.StatusBadRequest, "role must be user, moderator or admin", nil)
+		return
+	}
+
+	cfg.changeRole(w, r, params.Role)
+}
+
+// revokeRoleHandler demotes {userID} back to a regular user.
+func (cfg *apiConfig) revokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.changeRole(w, r, roleUser)
+}
+
+func (cfg *apiConfig) changeRole(w http.ResponseWriter, r *http.Request, role string) {
+	userID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	// Otherwise the last admin could lock everyone out.
+	actorID := staffID(r)
+	if userID == actorID {
+		jsonError(w, http.StatusForbidden, "you can't change your own role", nil)
+		return
+	}
+
+	user, err := cfg.setUserRole(r.Context(), r, userID, uuid.NullUUID{UUID: actorID, Valid: true}, role)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonError(w, http.StatusNotFound, "user not found", err)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to change role", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newUser(user))
+}
+
+// listRoleChangesHandler returns the audit trail of role changes, newest
+// first, optionally only those of user_id. The next page is requested with
+// before=next_before.
+func (cfg *apiConfig) listRoleChangesHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Changes    []RoleChange `json:"changes"`
+		NextBefore *uuid.UUID   `json:"next_before"`
+	}
+
+	userID := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid user_id (must be UUID)", err)
+			return
+		}
+		userID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	limit := defaultRoleChangesPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxRoleChangesPage {
+			jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxRoleChangesPage), err)
+			return
+		}
+		limit = n
+	}
+
+	before := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("before"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid before (must be UUID)", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbChanges, err := cfg.db.GetRoleChanges(r.Context(), database.GetRoleChangesParams{
+		UserID:     userID,
+		Before:     before,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list role changes", err)
+		return
+	}
+
+	resp := response{Changes: make([]RoleChange, 0, len(dbChanges))}
+	for _, c := range dbChanges {
+		resp.Changes = append(resp.Changes, newRoleChange(c))
+	}
+	if len(dbChanges) == limit {
+		last := dbChanges[len(dbChanges)-1].ID
+		resp.NextBefore = &last
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Roles, from least to most privileged. Each role can do everything the
+// ones before it can.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+// hasRole reports whether role grants at least the required one.
+func hasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+type contextKey int
+
+const staffIDKey contextKey = iota
+
+// staffID returns the ID of the caller let through by requireRole.
+func staffID(r *http.Request) uuid.UUID {
+	id, _ := r.Context().Value(staffIDKey).(uuid.UUID)
+	return id
+}
+
+// requireRole only lets callers with at least the given role through. The
+// role in the token turns everyone else away without a query; the stored
+// role is still checked so that a revoked role stops working right away
+// rather than when the token expires.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bearerToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			jsonError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+			return
+		}
+
+		claims, err := auth.ParseJWT(bearerToken, cfg.JWTSecret)
+		if err != nil {
+			jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
+			return
+		}
+		if !hasRole(claims.Role, role) {
+			jsonError(w, http.StatusForbidden, "requires the "+role+" role", nil)
+			return
+		}
+
+		user, err := cfg.db.GetUserById(r.Context(), claims.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusUnauthorized, "user not found", err)
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to get user", err)
+			return
+		}
+		if !hasRole(user.Role, role) {
+			jsonError(w, http.StatusForbidden, "requires the "+role+" role", nil)
+			return
+		}
+		if !cfg.checkNotSuspended(w, r, user.ID) {
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), staffIDKey, user.ID)))
+	}
+}
+
+// setUserRole changes the role of a user and records the change, made by
+// actor or, when it is null, by the server. r is the request that asked
+// for it, nil for the server's own changes.
+func (cfg *apiConfig) setUserRole(ctx context.Context, r *http.Request, userID uuid.UUID, actor uuid.NullUUID, role string) (database.User, error) {
+	var user database.User
+	var oldRole string
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		oldRole, err = q.GetUserRoleForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.SetUserRole(ctx, database.SetUserRoleParams{ID: userID, Role: role})
+		if err != nil {
+			return err
+		}
+		if oldRole == role {
+			return nil
+		}
+
+		_, err = q.RecordRoleChange(ctx, database.RecordRoleChangeParams{
+			UserID:  userID,
+			ActorID: actor,
+			OldRole: oldRole,
+			NewRole: role,
+		})
+		return err
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if oldRole != role {
+		cfg.audit(r, auditEvent{
+			Action:   auditRoleChanged,
+			ActorID:  actor.UUID,
+			TargetID: userID,
+			Metadata: map[string]string{"old_role": oldRole, "new_role": role},
+		})
+	}
+	return user, nil
+}
+
+// bootstrapAdmin makes the user with the ADMIN_EMAIL address an admin, so
+// that there is someone to grant the other roles. It only does so while
+// there is no admin at all: emails aren't verified, so whoever holds the
+// address later must not be promoted.
+func (cfg *apiConfig) bootstrapAdmin(ctx context.Context, email string) error {
+	admins, err := cfg.db.CountUsersWithRole(ctx, roleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("no user with ADMIN_EMAIL %s yet: restart once they signed up", email)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := cfg.setUserRole(ctx, nil, user.ID, uuid.NullUUID{}, roleAdmin); err != nil {
+		return err
+	}
+	log.Printf("promoted %s to admin", user.Email)
+	return nil
+}
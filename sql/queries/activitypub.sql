@@ -0,0 +1,106 @@
+-- name: CreateActivityPubKey :exec
+INSERT INTO activitypub_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: GetActivityPubKey :one
+SELECT * FROM activitypub_keys
+WHERE user_id = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, inbox, public_key_id, public_key_pem, fetched_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (id) DO UPDATE
+SET inbox = EXCLUDED.inbox,
+    public_key_id = EXCLUDED.public_key_id,
+    public_key_pem = EXCLUDED.public_key_pem,
+    fetched_at = EXCLUDED.fetched_at
+RETURNING *;
+
+-- name: GetRemoteActor :one
+SELECT * FROM remote_actors
+WHERE id = $1;
+
+-- name: DeleteRemoteActor :exec
+DELETE FROM remote_actors
+WHERE id = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, follow_activity_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET follow_activity_id = EXCLUDED.follow_activity_id;
+
+-- name: DeleteRemoteFollower :execrows
+DELETE FROM remote_followers
+WHERE user_id = $1 AND actor_id = $2;
+
+-- name: DeleteRemoteFollowerByFollowID :execrows
+DELETE FROM remote_followers
+WHERE actor_id = $1 AND follow_activity_id = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: CreateRemoteNote :exec
+INSERT INTO remote_notes (id, user_id, actor_id, content, in_reply_to, published_at, received_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteRemoteNote :execrows
+DELETE FROM remote_notes
+WHERE id = $1 AND actor_id = $2;
+
+-- name: EnqueueDelivery :exec
+INSERT INTO activitypub_deliveries (id, created_at, user_id, inbox, payload, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, NOW());
+
+-- name: EnqueueDeliveryToFollowers :execrows
+-- One delivery per distinct inbox of the user's remote followers.
+INSERT INTO activitypub_deliveries (id, created_at, user_id, inbox, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), sqlc.arg(user_id), inbox, sqlc.arg(payload), NOW()
+FROM (
+    SELECT DISTINCT ra.inbox
+    FROM remote_followers rf
+    JOIN remote_actors ra ON ra.id = rf.actor_id
+    WHERE rf.user_id = sqlc.arg(user_id)
+) AS inboxes;
+
+-- name: ClaimDueDeliveries :many
+-- Claimed deliveries are leased for a few minutes so that other instances
+-- skip them; a crash mid-delivery only delays the retry.
+UPDATE activitypub_deliveries
+SET next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE id IN (
+    SELECT id
+    FROM activitypub_deliveries
+    WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkDeliveryDelivered :exec
+UPDATE activitypub_deliveries
+SET delivered_at = NOW(), attempts = attempts + 1, last_error = ''
+WHERE id = $1;
+
+-- name: RetryDelivery :exec
+UPDATE activitypub_deliveries
+SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+WHERE id = $1;
+
+-- name: FailDelivery :exec
+UPDATE activitypub_deliveries
+SET attempts = attempts + 1, last_error = $2, failed_at = NOW()
+WHERE id = $1;
+
+-- name: LockActivityPubCursor :one
+SELECT last_event_id FROM activitypub_cursor
+FOR UPDATE;
+
+-- name: SetActivityPubCursor :exec
+UPDATE activitypub_cursor
+SET last_event_id = $1;
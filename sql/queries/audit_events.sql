@@ -0,0 +1,16 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, action, actor_id, target_id, ip, user_agent, metadata)
+VALUES (gen_random_uuid(), NOW(), sqlc.arg(action), sqlc.narg(actor_id), sqlc.narg(target_id), sqlc.arg(ip), sqlc.arg(user_agent), sqlc.arg(metadata));
+
+-- name: GetAuditEvents :many
+-- Keyset pagination: before is the id of the last event of the previous
+-- page.
+SELECT * FROM audit_events
+WHERE (sqlc.arg(action)::text = '' OR action = sqlc.arg(action))
+  AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(target_id)::uuid IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(before)::uuid IS NULL OR (created_at, id) < (
+      SELECT b.created_at, b.id FROM audit_events b WHERE b.id = sqlc.narg(before)
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
@@ -0,0 +1,21 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlocksByUser :many
+SELECT *
+FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_blocks
+    WHERE blocker_id = $1 AND blocked_id = $2
+);
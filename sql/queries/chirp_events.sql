@@ -0,0 +1,35 @@
+-- name: GetChirpEventsAfter :many
+-- Returns the events that follow after_id in the order of the transactions
+-- that recorded them. Events are only returned once every older
+-- transaction has ended, so nothing can show up before them afterwards.
+-- An after_id that was pruned is compared by id alone.
+SELECT chirp_events.* FROM chirp_events
+LEFT JOIN chirp_events after_event ON after_event.id = sqlc.arg(after_id)
+WHERE chirp_events.xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+  AND (
+      (chirp_events.xid, chirp_events.id) > (after_event.xid, after_event.id)
+      OR (after_event.id IS NULL AND chirp_events.id > sqlc.arg(after_id))
+  )
+ORDER BY chirp_events.xid ASC, chirp_events.id ASC
+LIMIT sqlc.arg(max_events);
+
+-- name: GetLatestChirpEventID :one
+-- The last event GetChirpEventsAfter can return at the moment.
+SELECT COALESCE((
+    SELECT id FROM chirp_events
+    WHERE xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+    ORDER BY xid DESC, id DESC
+    LIMIT 1
+), 0)::bigint AS id;
+
+-- name: HasHeldChirpEvents :one
+-- Reports whether committed events are held back by an older transaction
+-- that is still running.
+SELECT EXISTS (
+    SELECT 1 FROM chirp_events
+    WHERE xid >= pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+);
+
+-- name: PruneChirpEvents :execrows
+DELETE FROM chirp_events
+WHERE created_at < NOW() - sqlc.arg(retention_seconds)::bigint * INTERVAL '1 second';
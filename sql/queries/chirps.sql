@@ -1,30 +1,164 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of_id, publish_at, content_warning, sensitive)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT *
 FROM chirps
+WHERE publish_at IS NULL AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpsByUserId :many
 SELECT *
 FROM chirps
-WHERE user_id = $1
+WHERE user_id = $1 AND publish_at IS NULL AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
+-- name: GetChirpsByHashtag :many
+-- Only returns what anyone may read, so that the limit isn't spent on
+-- chirps filtered out afterwards: no hidden chirps, and no chirps of
+-- private, suspended or limited users.
+SELECT chirps.*
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.publish_at IS NULL AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND NOT users.is_private
+  AND NOT EXISTS (
+      SELECT 1 FROM suspensions
+      WHERE suspensions.user_id = chirps.user_id
+        AND suspensions.lifted_at IS NULL
+        AND suspensions.ends_at > NOW()
+  )
+  AND chirps.body ~* ('(^|\W)#' || sqlc.arg(tag)::text || '(\W|$)')
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(max_results);
+
 -- name: GetChirp :one
 SELECT *
 FROM chirps
 WHERE id = $1;
 
--- name: DeleteChirp :exec
+-- name: SoftDeleteChirp :exec
+-- Unpins the chirp too; restoring it doesn't pin it again.
+WITH unpinned AS (
+  UPDATE users
+  SET
+    pinned_chirp_id = NULL,
+    updated_at      = NOW()
+  WHERE pinned_chirp_id = $1
+)
+UPDATE chirps
+SET
+  deleted_at = NOW(),
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreChirp :execrows
+UPDATE chirps
+SET
+  deleted_at = NULL,
+  updated_at = NOW()
+WHERE id = sqlc.arg(id)
+  AND deleted_at > NOW() - sqlc.arg(window_seconds)::bigint * INTERVAL '1 second';
+
+-- name: PurgeDeletedChirps :execrows
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE deleted_at < NOW() - sqlc.arg(window_seconds)::bigint * INTERVAL '1 second';
+
+-- name: GetChirpsByUserIDs :many
+SELECT *
+FROM chirps
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[]) AND publish_at IS NULL AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: CreateRechirp :one
+-- Returns nothing when the user already rechirped the chirp.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
+SELECT *
+FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2;
+
+-- name: GetRechirpCounts :many
+SELECT rechirp_of_id, COUNT(*)
+FROM chirps
+WHERE rechirp_of_id = ANY(sqlc.arg(ids)::uuid[]) AND deleted_at IS NULL
+GROUP BY rechirp_of_id;
+
+-- name: GetScheduledChirpsByUserId :many
+SELECT *
+FROM chirps
+WHERE user_id = $1 AND publish_at IS NOT NULL AND deleted_at IS NULL
+ORDER BY publish_at ASC;
+
+-- name: PublishDueChirps :many
+UPDATE chirps
+SET
+  created_at = NOW(),
+  updated_at = NOW(),
+  publish_at = NULL
+WHERE id IN (
+    SELECT id
+    FROM chirps
+    WHERE publish_at IS NOT NULL AND publish_at <= NOW() AND deleted_at IS NULL
+    ORDER BY publish_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetChirpSensitive :one
+UPDATE chirps
+SET
+  sensitive  = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountChirpsSince :one
+-- Chirps a user wrote since a given time, scheduled ones included.
+SELECT COUNT(*) FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND rechirp_of_id IS NULL
+  AND created_at >= sqlc.arg(since);
+
+-- name: SetChirpHidden :one
+UPDATE chirps
+SET
+  hidden_at  = CASE WHEN sqlc.arg(hidden)::boolean THEN COALESCE(hidden_at, NOW()) END,
+  updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,89 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: AddConversationParticipants :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at, last_read_at)
+SELECT sqlc.arg(conversation_id)::uuid, unnest(sqlc.arg(user_ids)::uuid[]), NOW(), NOW();
+
+-- name: GetConversation :one
+SELECT *
+FROM conversations
+WHERE id = $1;
+
+-- name: GetDirectConversation :one
+SELECT conversations.*
+FROM conversations
+JOIN conversation_participants p1
+    ON p1.conversation_id = conversations.id AND p1.user_id = $1
+JOIN conversation_participants p2
+    ON p2.conversation_id = conversations.id AND p2.user_id = $2
+WHERE (
+    SELECT COUNT(*)
+    FROM conversation_participants p
+    WHERE p.conversation_id = conversations.id
+) = 2
+LIMIT 1;
+
+-- name: GetConversationsForUser :many
+SELECT
+    conversations.id,
+    conversations.created_at,
+    conversations.updated_at,
+    (
+        SELECT COUNT(*)
+        FROM messages m
+        WHERE m.conversation_id = conversations.id
+          AND m.sender_id <> cp.user_id
+          AND (m.created_at, m.id) > (cp.last_read_at, COALESCE(cp.last_read_message_id, '00000000-0000-0000-0000-000000000000'))
+    ) AS unread_count
+FROM conversations
+JOIN conversation_participants cp ON cp.conversation_id = conversations.id
+WHERE cp.user_id = $1
+ORDER BY conversations.updated_at DESC;
+
+-- name: GetConversationParticipantIDs :many
+SELECT user_id
+FROM conversation_participants
+WHERE conversation_id = $1
+ORDER BY joined_at ASC;
+
+-- name: IsConversationParticipant :one
+SELECT EXISTS (
+    SELECT 1
+    FROM conversation_participants
+    WHERE conversation_id = $1 AND user_id = $2
+);
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkConversationRead :exec
+-- Marks the messages up to the latest one as read. Messages are ordered
+-- by (created_at, id), so one sent in the same instant is told apart
+-- from it rather than compared with the time of the mark-read.
+UPDATE conversation_participants
+SET last_read_at = latest.created_at, last_read_message_id = latest.id
+FROM (
+    SELECT created_at, id FROM messages
+    WHERE conversation_id = $1
+    ORDER BY created_at DESC, id DESC
+    LIMIT 1
+) latest
+WHERE conversation_participants.conversation_id = $1
+  AND conversation_participants.user_id = $2;
+
+-- name: GetUnreadMessageCount :one
+SELECT COUNT(*)
+FROM messages m
+JOIN conversation_participants cp ON cp.conversation_id = m.conversation_id
+WHERE cp.user_id = $1
+  AND m.sender_id <> cp.user_id
+  AND (m.created_at, m.id) > (cp.last_read_at, COALESCE(cp.last_read_message_id, '00000000-0000-0000-0000-000000000000'));
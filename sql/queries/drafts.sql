@@ -0,0 +1,33 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT *
+FROM drafts
+WHERE id = $1;
+
+-- name: GetDraftsByUserId :many
+SELECT *
+FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET
+  body       = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDraft :exec
+DELETE FROM drafts
+WHERE id = $1;
@@ -0,0 +1,61 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id
+FROM follows
+WHERE follower_id = $1;
+
+-- name: CreateFollowRequest :exec
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2;
+
+-- name: GetFollowRequestsForUser :many
+SELECT *
+FROM follow_requests
+WHERE target_id = $1
+ORDER BY created_at ASC;
+
+-- name: ApproveFollowRequest :execrows
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE requester_id = $1 AND target_id = $2
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW()
+FROM approved
+ON CONFLICT DO NOTHING;
+
+-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE target_id = $1
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW()
+FROM approved
+ON CONFLICT DO NOTHING
+RETURNING follower_id;
+
+-- name: RemoveRelationshipsBetween :exec
+WITH removed_follows AS (
+    DELETE FROM follows
+    WHERE (follows.follower_id = $1 AND follows.followee_id = $2)
+       OR (follows.follower_id = $2 AND follows.followee_id = $1)
+)
+DELETE FROM follow_requests
+WHERE (follow_requests.requester_id = $1 AND follow_requests.target_id = $2)
+   OR (follow_requests.requester_id = $2 AND follow_requests.target_id = $1);
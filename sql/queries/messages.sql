@@ -0,0 +1,16 @@
+-- name: CreateMessage :one
+INSERT INTO messages (id, conversation_id, sender_id, body, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetMessages :many
+SELECT *
+FROM messages
+WHERE conversation_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,19 @@
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutesByUser :many
+SELECT *
+FROM user_mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetMutedUserIDs :many
+SELECT muted_id
+FROM user_mutes
+WHERE muter_id = $1;
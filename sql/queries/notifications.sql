@@ -0,0 +1,66 @@
+-- name: CreateNotification :execrows
+-- Nothing is recorded for one's own actions, nor for actions of users the
+-- recipient blocked, was blocked by or muted.
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id)
+SELECT gen_random_uuid(), NOW(), sqlc.arg(user_id), sqlc.arg(type), sqlc.narg(actor_id), sqlc.narg(chirp_id)
+WHERE sqlc.narg(actor_id)::uuid IS NULL OR (
+    sqlc.narg(actor_id) <> sqlc.arg(user_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM user_blocks
+        WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = sqlc.narg(actor_id))
+           OR (blocker_id = sqlc.narg(actor_id) AND blocked_id = sqlc.arg(user_id))
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM user_mutes
+        WHERE muter_id = sqlc.arg(user_id) AND muted_id = sqlc.narg(actor_id)
+    )
+);
+
+-- name: GetNotifications :many
+-- Keyset pagination: before is the id of the last notification of the
+-- previous page.
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.arg(type)::text = '' OR type = sqlc.arg(type))
+  AND (chirp_id IS NULL OR EXISTS (
+      SELECT 1 FROM chirps
+      WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NULL
+  ))
+  AND (sqlc.narg(before)::uuid IS NULL OR (created_at, id) < (
+      SELECT b.created_at, b.id FROM notifications b WHERE b.id = sqlc.narg(before)
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetNotification :one
+-- Like GetNotifications, leaves out notifications about deleted chirps.
+SELECT * FROM notifications
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id)
+  AND (chirp_id IS NULL OR EXISTS (
+      SELECT 1 FROM chirps
+      WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NULL
+  ));
+
+-- name: GetUnreadNotificationCounts :many
+-- Counts what GetNotifications would list.
+SELECT type, COUNT(*) AS count
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+  AND (chirp_id IS NULL OR EXISTS (
+      SELECT 1 FROM chirps
+      WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NULL
+  ))
+GROUP BY type
+ORDER BY type;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+  AND read_at IS NULL
+  AND (sqlc.arg(type)::text = '' OR type = sqlc.arg(type));
@@ -0,0 +1,52 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, created_at, chirp_id, closes_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: CreatePollOptions :exec
+INSERT INTO poll_options (id, poll_id, position, text)
+SELECT gen_random_uuid(), sqlc.arg(poll_id)::uuid, o.position, o.text
+FROM unnest(sqlc.arg(texts)::text[]) WITH ORDINALITY AS o(text, position);
+
+-- name: GetPollByChirpID :one
+SELECT *
+FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollsByChirpIDs :many
+SELECT *
+FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionResults :many
+SELECT
+    poll_options.id,
+    poll_options.poll_id,
+    poll_options.position,
+    poll_options.text,
+    COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.poll_id = ANY(sqlc.arg(poll_ids)::uuid[])
+GROUP BY poll_options.id
+ORDER BY poll_options.poll_id, poll_options.position;
+
+-- name: GetPollOption :one
+SELECT *
+FROM poll_options
+WHERE id = $1;
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, option_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetUserPollVotes :many
+SELECT poll_id, option_id
+FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND poll_id = ANY(sqlc.arg(poll_ids)::uuid[]);
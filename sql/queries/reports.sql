@@ -0,0 +1,35 @@
+-- name: CreateReport :one
+-- Returns nothing when the reporter already has an open report about the
+-- same user or chirp.
+INSERT INTO reports (id, created_at, reporter_id, user_id, chirp_id, reason, details)
+VALUES (gen_random_uuid(), NOW(), sqlc.arg(reporter_id), sqlc.arg(user_id), sqlc.narg(chirp_id), sqlc.arg(reason), sqlc.arg(details))
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReports :many
+-- Keyset pagination: before is the id of the last report of the previous
+-- page.
+SELECT * FROM reports
+WHERE (sqlc.arg(status)::text = '' OR status = sqlc.arg(status))
+  AND (sqlc.arg(reason)::text = '' OR reason = sqlc.arg(reason))
+  AND (sqlc.narg(before)::uuid IS NULL OR (created_at, id) < (
+      SELECT b.created_at, b.id FROM reports b WHERE b.id = sqlc.narg(before)
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: ResolveReport :one
+-- Returns nothing when the report was already resolved.
+UPDATE reports
+SET
+  status      = 'resolved',
+  action      = sqlc.arg(action),
+  note        = sqlc.arg(note),
+  resolved_by = sqlc.arg(resolved_by),
+  resolved_at = NOW()
+WHERE id = sqlc.arg(id) AND status = 'open'
+RETURNING *;
@@ -0,0 +1,32 @@
+-- name: GetUserRoleForUpdate :one
+SELECT role FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetUserRole :one
+UPDATE users
+SET
+  role       = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, actor_id, old_role, new_role)
+VALUES (gen_random_uuid(), NOW(), sqlc.arg(user_id), sqlc.arg(actor_id), sqlc.arg(old_role), sqlc.arg(new_role))
+RETURNING *;
+
+-- name: GetRoleChanges :many
+-- Keyset pagination: before is the id of the last change of the previous
+-- page. A null user_id lists the changes of every user.
+SELECT * FROM role_changes
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(before)::uuid IS NULL OR (created_at, id) < (
+      SELECT b.created_at, b.id FROM role_changes b WHERE b.id = sqlc.narg(before)
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1;
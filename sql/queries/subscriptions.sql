@@ -0,0 +1,32 @@
+-- name: GetOpenSubscriptionPeriod :one
+SELECT * FROM subscription_periods
+WHERE user_id = $1 AND ended_at IS NULL;
+
+-- name: OpenSubscriptionPeriod :one
+INSERT INTO subscription_periods (id, user_id, started_at, ends_at)
+VALUES (gen_random_uuid(), $1, $2, $3)
+RETURNING *;
+
+-- name: EndSubscriptionPeriod :execrows
+UPDATE subscription_periods
+SET ended_at = NOW(), end_reason = sqlc.arg(end_reason)
+WHERE user_id = sqlc.arg(user_id) AND ended_at IS NULL;
+
+-- name: SetSubscriptionPastDue :execrows
+UPDATE subscription_periods
+SET status = 'past_due'
+WHERE user_id = $1 AND ended_at IS NULL;
+
+-- name: ExpireLapsedSubscriptions :many
+-- Ends the periods that ran out without a renewal and takes Chirpy Red
+-- away from their users.
+WITH lapsed AS (
+    UPDATE subscription_periods
+    SET ended_at = NOW(), end_reason = 'expired'
+    WHERE ended_at IS NULL AND ends_at <= NOW()
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = FALSE, updated_at = NOW()
+WHERE id IN (SELECT user_id FROM lapsed)
+RETURNING id;
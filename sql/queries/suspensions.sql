@@ -0,0 +1,28 @@
+-- name: CreateSuspension :one
+INSERT INTO suspensions (id, created_at, user_id, kind, reason, ends_at, created_by, report_id)
+VALUES (gen_random_uuid(), NOW(), sqlc.arg(user_id), sqlc.arg(kind), sqlc.arg(reason), sqlc.arg(ends_at), sqlc.narg(created_by), sqlc.narg(report_id))
+RETURNING *;
+
+-- name: GetActiveSuspension :one
+-- Suspensions take precedence over limits, then the one that ends last.
+SELECT * FROM suspensions
+WHERE user_id = $1 AND lifted_at IS NULL AND ends_at > NOW()
+ORDER BY kind = 'suspended' DESC, ends_at DESC
+LIMIT 1;
+
+-- name: GetRestrictedUserIDs :many
+-- Among ids, the users currently suspended or limited.
+SELECT DISTINCT user_id FROM suspensions
+WHERE user_id = ANY(sqlc.arg(ids)::uuid[])
+  AND lifted_at IS NULL
+  AND ends_at > NOW();
+
+-- name: GetSuspensions :many
+SELECT * FROM suspensions
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: LiftSuspensions :execrows
+UPDATE suspensions
+SET lifted_at = NOW(), lifted_by = sqlc.narg(lifted_by)
+WHERE user_id = sqlc.arg(user_id) AND lifted_at IS NULL AND ends_at > NOW();
@@ -33,8 +33,52 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: DowngradeFromChirpyRed :one
+UPDATE users
+SET
+  is_chirpy_red = FALSE,
+  updated_at     = NOW()
+WHERE id = $1
+RETURNING *;
+
 
 -- name: GetUserById :one
 SELECT *
 FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUsersByIDs :many
+SELECT *
+FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: SetUserPrivacy :one
+UPDATE users
+SET
+  is_private = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetPinnedChirp :one
+UPDATE users
+SET
+  pinned_chirp_id = $2,
+  updated_at      = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnpinChirp :execrows
+UPDATE users
+SET
+  pinned_chirp_id = NULL,
+  updated_at      = NOW()
+WHERE id = $1 AND pinned_chirp_id = $2;
+
+-- name: SetCollapseSensitive :one
+UPDATE users
+SET
+  collapse_sensitive = $2,
+  updated_at         = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,93 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, created_at, user_id, url, secret, events)
+VALUES (gen_random_uuid(), NOW(), sqlc.narg(user_id), sqlc.arg(url), sqlc.arg(secret), sqlc.arg(events)::text[])
+RETURNING *;
+
+-- name: GetWebhookEndpoints :many
+-- Endpoints of a user, or the admin endpoints when user_id is NULL.
+SELECT * FROM webhook_endpoints
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+ORDER BY created_at ASC;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = sqlc.arg(id) AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id);
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = sqlc.arg(id) AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id);
+
+-- name: EnqueueWebhookEvent :execrows
+-- One delivery per endpoint subscribed to the event: the admin ones, and
+-- those of the user the event is about.
+INSERT INTO webhook_deliveries (id, created_at, endpoint_id, event, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), id, sqlc.arg(event), sqlc.arg(payload), NOW()
+FROM webhook_endpoints
+WHERE (user_id IS NULL OR user_id = sqlc.arg(user_id))
+  AND sqlc.arg(event) = ANY(events);
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, endpoint_id, event, payload, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, NOW())
+RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Claimed deliveries are leased for a few minutes so that other instances
+-- skip them; a crash mid-delivery only delays the retry.
+UPDATE webhook_deliveries d
+SET next_attempt_at = NOW() + INTERVAL '5 minutes'
+FROM webhook_endpoints e
+WHERE e.id = d.endpoint_id AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at ASC
+    LIMIT sqlc.arg(max_deliveries)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.event, d.payload, d.attempts, e.url, e.secret;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, delivery_id, attempted_at, status_code, error, duration_ms)
+VALUES (gen_random_uuid(), $1, NOW(), $2, $3, $4);
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id = $1;
+
+-- name: KillWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'dead', attempts = attempts + 1
+WHERE id = $1;
+
+-- name: RequeueWebhookDelivery :execrows
+-- Gives a dead delivery a fresh set of attempts.
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE id = sqlc.arg(id) AND endpoint_id = sqlc.arg(endpoint_id) AND status = 'dead';
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg(endpoint_id)
+  AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = ANY(sqlc.arg(delivery_ids)::uuid[])
+ORDER BY attempted_at ASC;
+
+-- name: LockWebhookCursor :one
+SELECT last_event_id FROM webhook_cursor
+FOR UPDATE;
+
+-- name: SetWebhookCursor :exec
+UPDATE webhook_cursor
+SET last_event_id = $1;
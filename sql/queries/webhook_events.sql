@@ -0,0 +1,39 @@
+-- name: RecordWebhookEvent :one
+-- Returns nothing for an event that was already received, unless its
+-- processing failed, or was abandoned stale_seconds ago by a request that
+-- never recorded the outcome: a redelivery is then another attempt.
+-- received_at is bumped so that the attempt isn't taken for stale itself.
+INSERT INTO webhook_events (id, received_at, event, payload, provider)
+VALUES (sqlc.arg(id), NOW(), sqlc.arg(event), sqlc.arg(payload), sqlc.arg(provider))
+ON CONFLICT (id) DO UPDATE
+SET status = 'received', received_at = NOW(), attempts = webhook_events.attempts + 1
+WHERE webhook_events.status = 'failed'
+   OR (webhook_events.status = 'received'
+       AND webhook_events.received_at < NOW() - sqlc.arg(stale_seconds)::bigint * INTERVAL '1 second')
+RETURNING *;
+
+-- name: ReplayWebhookEvent :one
+UPDATE webhook_events
+SET status = 'received', received_at = NOW(), attempts = attempts + 1
+WHERE id = $1 AND status = 'failed'
+RETURNING *;
+
+-- name: SetWebhookEventResult :exec
+UPDATE webhook_events
+SET status = sqlc.arg(status), error = sqlc.arg(error), processed_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: GetWebhookEvents :many
+-- Keyset pagination: before is the id of the last event of the previous
+-- page.
+SELECT * FROM webhook_events
+WHERE (sqlc.arg(status)::text = '' OR status = sqlc.arg(status))
+  AND (sqlc.arg(before)::text = '' OR (received_at, id) < (
+      SELECT b.received_at, b.id FROM webhook_events b WHERE b.id = sqlc.arg(before)
+  ))
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
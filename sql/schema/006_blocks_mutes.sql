@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_blocks(
+    blocker_id  uuid NOT NULL,
+    blocked_id  uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT fk_block_blocker
+        FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_block_blocked
+        FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_mutes(
+    muter_id    uuid NOT NULL,
+    muted_id    uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CONSTRAINT fk_mute_muter
+        FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mute_muted
+        FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_mutes;
+DROP TABLE IF EXISTS user_blocks;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS follows(
+    follower_id uuid NOT NULL,
+    followee_id uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT fk_follow_follower
+        FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_follow_followee
+        FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS follow_requests(
+    requester_id uuid NOT NULL,
+    target_id    uuid NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (requester_id, target_id),
+    CONSTRAINT fk_follow_request_requester
+        FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_follow_request_target
+        FOREIGN KEY (target_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS follow_requests;
+DROP TABLE IF EXISTS follows;
+ALTER TABLE users DROP COLUMN is_private;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS conversations(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS conversation_participants(
+    conversation_id uuid NOT NULL,
+    user_id         uuid NOT NULL,
+    joined_at       TIMESTAMP NOT NULL,
+    last_read_at    TIMESTAMP NOT NULL,
+    PRIMARY KEY (conversation_id, user_id),
+    CONSTRAINT fk_participant_conversation
+        FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_participant_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS messages(
+    id              uuid PRIMARY KEY,
+    conversation_id uuid NOT NULL,
+    sender_id       uuid NOT NULL,
+    body            TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL,
+    CONSTRAINT fk_message_conversation
+        FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_message_sender
+        FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_created_at
+    ON messages (conversation_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_participants;
+DROP TABLE IF EXISTS conversations;
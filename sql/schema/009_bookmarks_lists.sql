@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS bookmarks(
+    user_id     uuid NOT NULL,
+    chirp_id    uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    CONSTRAINT fk_bookmark_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_chirp
+        FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS lists(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL,
+    owner_id    uuid NOT NULL,
+    name        TEXT NOT NULL,
+    is_private  BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT fk_list_owner
+        FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS list_members(
+    list_id     uuid NOT NULL,
+    user_id     uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (list_id, user_id),
+    CONSTRAINT fk_list_member_list
+        FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE,
+    CONSTRAINT fk_list_member_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS list_members;
+DROP TABLE IF EXISTS lists;
+DROP TABLE IF EXISTS bookmarks;
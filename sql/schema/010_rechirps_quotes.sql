@@ -0,0 +1,14 @@
+-- +goose Up
+-- Rechirps disappear with their original; quotes keep the reference so the
+-- API can report the quoted chirp as unavailable.
+ALTER TABLE chirps ADD COLUMN rechirp_of_id uuid REFERENCES chirps(id) ON DELETE CASCADE;
+ALTER TABLE chirps ADD COLUMN quote_of_id uuid;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chirps_one_rechirp_per_user
+    ON chirps (user_id, rechirp_of_id)
+    WHERE rechirp_of_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_one_rechirp_per_user;
+ALTER TABLE chirps DROP COLUMN quote_of_id;
+ALTER TABLE chirps DROP COLUMN rechirp_of_id;
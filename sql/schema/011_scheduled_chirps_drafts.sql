@@ -0,0 +1,23 @@
+-- +goose Up
+-- A chirp with publish_at set is scheduled: only its author can see it until
+-- the scheduler publishes it and clears the column.
+ALTER TABLE chirps ADD COLUMN publish_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_chirps_publish_at
+    ON chirps (publish_at)
+    WHERE publish_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS drafts(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL,
+    user_id     uuid NOT NULL,
+    body        TEXT NOT NULL,
+    CONSTRAINT fk_draft_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS drafts;
+DROP INDEX IF EXISTS idx_chirps_publish_at;
+ALTER TABLE chirps DROP COLUMN publish_at;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS polls(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    chirp_id    uuid NOT NULL UNIQUE,
+    closes_at   TIMESTAMP NOT NULL,
+    CONSTRAINT fk_poll_chirp
+        FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_options(
+    id          uuid PRIMARY KEY,
+    poll_id     uuid NOT NULL,
+    position    INTEGER NOT NULL,
+    text        TEXT NOT NULL,
+    CONSTRAINT fk_poll_option_poll
+        FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes(
+    poll_id     uuid NOT NULL,
+    user_id     uuid NOT NULL,
+    option_id   uuid NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (poll_id, user_id),
+    CONSTRAINT fk_poll_vote_poll
+        FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_vote_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_vote_option
+        FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
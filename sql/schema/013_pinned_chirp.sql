@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN pinned_chirp_id uuid REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN pinned_chirp_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN content_warning TEXT NOT NULL DEFAULT '';
+ALTER TABLE chirps ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN collapse_sensitive BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN collapse_sensitive;
+ALTER TABLE chirps DROP COLUMN sensitive;
+ALTER TABLE chirps DROP COLUMN content_warning;
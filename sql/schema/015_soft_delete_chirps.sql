@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_chirps_deleted_at
+    ON chirps (deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_deleted_at;
+ALTER TABLE chirps DROP COLUMN deleted_at;
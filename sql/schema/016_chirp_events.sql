@@ -0,0 +1,70 @@
+-- +goose Up
+-- chirp_events is a short-lived log of chirps appearing in or disappearing
+-- from listings. Its ids back the Last-Event-ID of the SSE stream, and every
+-- insert is announced with NOTIFY so that all instances can fan it out.
+CREATE TABLE IF NOT EXISTS chirp_events(
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    kind        TEXT NOT NULL,
+    chirp_id    uuid NOT NULL,
+    user_id     uuid NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chirp_events_created_at
+    ON chirp_events (created_at);
+
+-- A chirp is listed once it is neither scheduled nor deleted. Inserts,
+-- scheduled publications, soft deletes, restores and hard deletes all go
+-- through this trigger, so no handler has to remember to emit events.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_chirp_event() RETURNS trigger AS $$
+DECLARE
+    was_listed BOOLEAN := FALSE;
+    is_listed  BOOLEAN := FALSE;
+    event_kind TEXT;
+    event_id   BIGINT;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        was_listed := OLD.publish_at IS NULL AND OLD.deleted_at IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        is_listed := NEW.publish_at IS NULL AND NEW.deleted_at IS NULL;
+    END IF;
+
+    IF is_listed AND NOT was_listed THEN
+        event_kind := 'created';
+    ELSIF was_listed AND NOT is_listed THEN
+        event_kind := 'deleted';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, OLD.id, OLD.user_id)
+        RETURNING id INTO event_id;
+    ELSE
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, NEW.id, NEW.user_id)
+        RETURNING id INTO event_id;
+    END IF;
+
+    PERFORM pg_notify('chirp_events', json_build_object(
+        'id', event_id,
+        'kind', event_kind,
+        'chirp_id', COALESCE(NEW.id, OLD.id),
+        'user_id', COALESCE(NEW.user_id, OLD.user_id)
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_record_event
+    AFTER INSERT OR UPDATE OR DELETE ON chirps
+    FOR EACH ROW EXECUTE FUNCTION record_chirp_event();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_record_event ON chirps;
+DROP FUNCTION IF EXISTS record_chirp_event();
+DROP TABLE IF EXISTS chirp_events;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS notifications(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    user_id     uuid NOT NULL,
+    type        TEXT NOT NULL,
+    actor_id    uuid,
+    chirp_id    uuid,
+    read_at     TIMESTAMP,
+    CONSTRAINT fk_notification_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notification_actor
+        FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notification_chirp
+        FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at
+    ON notifications (user_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+    ON notifications (user_id)
+    WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
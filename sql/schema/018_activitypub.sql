@@ -0,0 +1,85 @@
+-- +goose Up
+-- Key pairs of local users, created the first time they are federated.
+CREATE TABLE IF NOT EXISTS activitypub_keys(
+    user_id         uuid PRIMARY KEY,
+    created_at      TIMESTAMP NOT NULL,
+    public_key_pem  TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL,
+    CONSTRAINT fk_activitypub_key_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Remote actors are identified by their URI.
+CREATE TABLE IF NOT EXISTS remote_actors(
+    id              TEXT PRIMARY KEY,
+    inbox           TEXT NOT NULL,
+    public_key_id   TEXT NOT NULL UNIQUE,
+    public_key_pem  TEXT NOT NULL,
+    fetched_at      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS remote_followers(
+    user_id             uuid NOT NULL,
+    actor_id            TEXT NOT NULL,
+    follow_activity_id  TEXT NOT NULL,
+    created_at          TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, actor_id),
+    CONSTRAINT fk_remote_follower_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_remote_follower_actor
+        FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+
+-- Notes received in the inbox of a local user.
+CREATE TABLE IF NOT EXISTS remote_notes(
+    id              TEXT NOT NULL,
+    user_id         uuid NOT NULL,
+    actor_id        TEXT NOT NULL,
+    content         TEXT NOT NULL,
+    in_reply_to     TEXT NOT NULL DEFAULT '',
+    published_at    TIMESTAMP NOT NULL,
+    received_at     TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, user_id),
+    CONSTRAINT fk_remote_note_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_remote_note_actor
+        FOREIGN KEY (actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE
+);
+
+-- Outbound queue. A delivery is pending until either delivered_at or
+-- failed_at is set.
+CREATE TABLE IF NOT EXISTS activitypub_deliveries(
+    id              uuid PRIMARY KEY,
+    created_at      TIMESTAMP NOT NULL,
+    user_id         uuid NOT NULL,
+    inbox           TEXT NOT NULL,
+    payload         TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error      TEXT NOT NULL DEFAULT '',
+    delivered_at    TIMESTAMP,
+    failed_at       TIMESTAMP,
+    CONSTRAINT fk_activitypub_delivery_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_activitypub_deliveries_pending
+    ON activitypub_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+-- How far the chirp_events log has been federated.
+CREATE TABLE IF NOT EXISTS activitypub_cursor(
+    id              BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_event_id   BIGINT NOT NULL
+);
+
+INSERT INTO activitypub_cursor (id, last_event_id)
+SELECT TRUE, COALESCE(MAX(id), 0) FROM chirp_events;
+
+-- +goose Down
+DROP TABLE IF EXISTS activitypub_cursor;
+DROP TABLE IF EXISTS activitypub_deliveries;
+DROP TABLE IF EXISTS remote_notes;
+DROP TABLE IF EXISTS remote_followers;
+DROP TABLE IF EXISTS remote_actors;
+DROP TABLE IF EXISTS activitypub_keys;
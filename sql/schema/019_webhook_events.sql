@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhook_events(
+    id            TEXT PRIMARY KEY,
+    received_at   TIMESTAMP NOT NULL,
+    event         TEXT NOT NULL,
+    payload       TEXT NOT NULL,
+    -- received, processed, ignored or failed.
+    status        TEXT NOT NULL DEFAULT 'received',
+    error         TEXT NOT NULL DEFAULT '',
+    attempts      INTEGER NOT NULL DEFAULT 1,
+    processed_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_received_at
+    ON webhook_events (received_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_events;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- One row per Chirpy Red billing period. A user has at most one open
+-- period; is_chirpy_red mirrors whether it exists.
+CREATE TABLE IF NOT EXISTS subscription_periods(
+    id          uuid PRIMARY KEY,
+    user_id     uuid NOT NULL,
+    started_at  TIMESTAMP NOT NULL,
+    ends_at     TIMESTAMP NOT NULL,
+    -- active, or past_due after a failed payment until the period ends.
+    status      TEXT NOT NULL DEFAULT 'active',
+    ended_at    TIMESTAMP,
+    -- renewed, downgraded or expired.
+    end_reason  TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_subscription_period_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_periods_open
+    ON subscription_periods (user_id)
+    WHERE ended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subscription_periods_ends_at
+    ON subscription_periods (ends_at)
+    WHERE ended_at IS NULL;
+
+-- Users upgraded before periods were tracked get one ending a month from
+-- now, like a fresh upgrade.
+INSERT INTO subscription_periods (id, user_id, started_at, ends_at)
+SELECT gen_random_uuid(), id, updated_at, NOW() + INTERVAL '30 days'
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE IF EXISTS subscription_periods;
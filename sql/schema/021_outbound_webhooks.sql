@@ -0,0 +1,67 @@
+-- +goose Up
+-- Endpoints without a user are registered by admins and receive every
+-- event; the others only receive events about their user.
+CREATE TABLE IF NOT EXISTS webhook_endpoints(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    user_id     uuid,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    events      TEXT[] NOT NULL,
+    CONSTRAINT fk_webhook_endpoint_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id
+    ON webhook_endpoints (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+    id              uuid PRIMARY KEY,
+    created_at      TIMESTAMP NOT NULL,
+    endpoint_id     uuid NOT NULL,
+    event           TEXT NOT NULL,
+    payload         TEXT NOT NULL,
+    -- pending, delivered, or dead once out of attempts.
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_webhook_delivery_endpoint
+        FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending
+    ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint
+    ON webhook_deliveries (endpoint_id, created_at DESC);
+
+-- One row per attempt, kept as the delivery log of the endpoint.
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts(
+    id           uuid PRIMARY KEY,
+    delivery_id  uuid NOT NULL,
+    attempted_at TIMESTAMP NOT NULL,
+    status_code  INTEGER NOT NULL,
+    error        TEXT NOT NULL DEFAULT '',
+    duration_ms  INTEGER NOT NULL,
+    CONSTRAINT fk_webhook_attempt_delivery
+        FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery
+    ON webhook_delivery_attempts (delivery_id, attempted_at);
+
+-- How far the chirp_events log has been turned into webhook deliveries.
+CREATE TABLE IF NOT EXISTS webhook_cursor(
+    id              BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_event_id   BIGINT NOT NULL
+);
+
+INSERT INTO webhook_cursor (id, last_event_id)
+SELECT TRUE, COALESCE(MAX(id), 0) FROM chirp_events;
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_cursor;
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Webhooks were only received from Polka so far.
+ALTER TABLE webhook_events
+    ADD COLUMN provider TEXT NOT NULL DEFAULT 'polka';
+
+-- +goose Down
+ALTER TABLE webhook_events
+    DROP COLUMN provider;
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- Audit trail of role grants and revocations. Changes without an actor
+-- were made by the server, e.g. when promoting ADMIN_EMAIL.
+CREATE TABLE IF NOT EXISTS role_changes(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    user_id     uuid NOT NULL,
+    actor_id    uuid,
+    old_role    TEXT NOT NULL,
+    new_role    TEXT NOT NULL,
+    CONSTRAINT fk_role_change_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_change_actor
+        FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_changes_created_at
+    ON role_changes (created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS role_changes;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,63 @@
+-- +goose Up
+-- Set when a moderator hides a chirp: only its author and moderators can
+-- still read it.
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+-- Reports are about a user, and about one of their chirps for chirp
+-- reports. They stay open until a moderator resolves them with an action.
+CREATE TABLE IF NOT EXISTS reports(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    reporter_id uuid NOT NULL,
+    user_id     uuid NOT NULL,
+    chirp_id    uuid,
+    reason      TEXT NOT NULL,
+    details     TEXT NOT NULL DEFAULT '',
+    -- open or resolved.
+    status      TEXT NOT NULL DEFAULT 'open',
+    -- dismiss, hide, suspend or warn once resolved.
+    action      TEXT NOT NULL DEFAULT '',
+    note        TEXT NOT NULL DEFAULT '',
+    resolved_by uuid,
+    resolved_at TIMESTAMP,
+    CONSTRAINT fk_report_reporter
+        FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_report_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_report_chirp
+        FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_report_resolved_by
+        FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A user can only have one open report about the same thing.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open
+    ON reports (reporter_id, user_id, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'))
+    WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_reports_created_at
+    ON reports (created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS suspensions(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    user_id     uuid NOT NULL,
+    reason      TEXT NOT NULL,
+    ends_at     TIMESTAMP NOT NULL,
+    created_by  uuid,
+    report_id   uuid,
+    CONSTRAINT fk_suspension_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_suspension_created_by
+        FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_suspension_report
+        FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_suspensions_user_id
+    ON suspensions (user_id, ends_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS suspensions;
+DROP TABLE IF EXISTS reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
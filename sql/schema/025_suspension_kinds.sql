@@ -0,0 +1,16 @@
+-- +goose Up
+-- Suspended users can't use the API; limited users still can, but their
+-- chirps are only visible to themselves. Either ends early once lifted.
+ALTER TABLE suspensions
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'suspended'
+        CHECK (kind IN ('suspended', 'limited')),
+    ADD COLUMN lifted_at TIMESTAMP,
+    ADD COLUMN lifted_by uuid,
+    ADD CONSTRAINT fk_suspension_lifted_by
+        FOREIGN KEY (lifted_by) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE suspensions
+    DROP COLUMN lifted_by,
+    DROP COLUMN lifted_at,
+    DROP COLUMN kind;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- Security-relevant actions. Users are referenced without foreign keys so
+-- that events outlive the users they are about.
+CREATE TABLE IF NOT EXISTS audit_events(
+    id          uuid PRIMARY KEY,
+    created_at  TIMESTAMP NOT NULL,
+    action      TEXT NOT NULL,
+    actor_id    uuid,
+    target_id   uuid,
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    -- A JSON object with details specific to the action.
+    metadata    TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at
+    ON audit_events (created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id
+    ON audit_events (target_id, created_at DESC);
+
+-- The table is append-only.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
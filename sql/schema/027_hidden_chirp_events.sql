@@ -0,0 +1,94 @@
+-- +goose Up
+-- Hidden chirps leave listings too: hiding a chirp records a deleted event,
+-- so that streams drop it and remote servers get a Delete, and unhiding it
+-- records a created event.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_chirp_event() RETURNS trigger AS $$
+DECLARE
+    was_listed BOOLEAN := FALSE;
+    is_listed  BOOLEAN := FALSE;
+    event_kind TEXT;
+    event_id   BIGINT;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        was_listed := OLD.publish_at IS NULL AND OLD.deleted_at IS NULL
+            AND OLD.hidden_at IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        is_listed := NEW.publish_at IS NULL AND NEW.deleted_at IS NULL
+            AND NEW.hidden_at IS NULL;
+    END IF;
+
+    IF is_listed AND NOT was_listed THEN
+        event_kind := 'created';
+    ELSIF was_listed AND NOT is_listed THEN
+        event_kind := 'deleted';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, OLD.id, OLD.user_id)
+        RETURNING id INTO event_id;
+    ELSE
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, NEW.id, NEW.user_id)
+        RETURNING id INTO event_id;
+    END IF;
+
+    PERFORM pg_notify('chirp_events', json_build_object(
+        'id', event_id,
+        'kind', event_kind,
+        'chirp_id', COALESCE(NEW.id, OLD.id),
+        'user_id', COALESCE(NEW.user_id, OLD.user_id)
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_chirp_event() RETURNS trigger AS $$
+DECLARE
+    was_listed BOOLEAN := FALSE;
+    is_listed  BOOLEAN := FALSE;
+    event_kind TEXT;
+    event_id   BIGINT;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        was_listed := OLD.publish_at IS NULL AND OLD.deleted_at IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        is_listed := NEW.publish_at IS NULL AND NEW.deleted_at IS NULL;
+    END IF;
+
+    IF is_listed AND NOT was_listed THEN
+        event_kind := 'created';
+    ELSIF was_listed AND NOT is_listed THEN
+        event_kind := 'deleted';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, OLD.id, OLD.user_id)
+        RETURNING id INTO event_id;
+    ELSE
+        INSERT INTO chirp_events (kind, chirp_id, user_id)
+        VALUES (event_kind, NEW.id, NEW.user_id)
+        RETURNING id INTO event_id;
+    END IF;
+
+    PERFORM pg_notify('chirp_events', json_build_object(
+        'id', event_id,
+        'kind', event_kind,
+        'chirp_id', COALESCE(NEW.id, OLD.id),
+        'user_id', COALESCE(NEW.user_id, OLD.user_id)
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Ids are handed out before commit, so a later id can become visible
+-- before an earlier one and a reader going by id alone skips the earlier
+-- one. Events are read in the order of the transactions that recorded
+-- them instead, once every older transaction has ended.
+ALTER TABLE chirp_events
+    ADD COLUMN xid BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX IF NOT EXISTS idx_chirp_events_xid
+    ON chirp_events (xid, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirp_events_xid;
+ALTER TABLE chirp_events DROP COLUMN xid;
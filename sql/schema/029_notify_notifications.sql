@@ -0,0 +1,22 @@
+-- +goose Up
+-- Every new notification is announced with NOTIFY, so that the instance
+-- holding its recipient's WebSocket can push it.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION announce_notification() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notifications', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER notifications_announce
+    AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE FUNCTION announce_notification();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS notifications_announce ON notifications;
+DROP FUNCTION IF EXISTS announce_notification();
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Messages read are tracked by the last one read, ordered by
+-- (created_at, id), instead of by the time they were marked read: a
+-- message sent in the same instant as a mark-read was otherwise counted
+-- either way. last_read_at becomes the created_at of that message, and
+-- last_read_message_id stays NULL until a participant reads one.
+ALTER TABLE conversation_participants ADD COLUMN last_read_message_id uuid;
+
+UPDATE conversation_participants cp
+SET last_read_message_id = (
+    SELECT m.id FROM messages m
+    WHERE m.conversation_id = cp.conversation_id
+      AND m.created_at = cp.last_read_at
+    ORDER BY m.id DESC
+    LIMIT 1
+);
+
+-- +goose Down
+ALTER TABLE conversation_participants DROP COLUMN last_read_message_id;
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/payments"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSubscriptionPeriod  = 30 * 24 * time.Hour
+	subscriptionExpiryInterval = time.Minute
+)
+
+// Why a subscription period ended.
+const (
+	periodRenewed    = "renewed"
+	periodDowngraded = "downgraded"
+	periodExpired    = "expired"
+)
+
+// applySubscriptionEvent updates the subscription periods of the user of a
+// payment event and their Chirpy Red flag with them.
+func (cfg *apiConfig) applySubscriptionEvent(ctx context.Context, ev payments.Event) (string, error) {
+	now := time.Now().UTC()
+	// The provider may tell when the paid period ends; otherwise it lasts a month.
+	periodEnd := func(from time.Time) time.Time {
+		if !ev.PeriodEnd.IsZero() {
+			return ev.PeriodEnd.UTC()
+		}
+		return from.Add(defaultSubscriptionPeriod)
+	}
+
+	status := webhookProcessed
+	upgraded := false
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		if _, err := q.GetUserById(ctx, ev.UserID); err != nil {
+			return err
+		}
+
+		open, err := q.GetOpenSubscriptionPeriod(ctx, ev.UserID)
+		hasOpen := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		switch ev.Type {
+		case payments.SubscriptionStarted, payments.SubscriptionRenewed:
+			start := now
+			if hasOpen {
+				if ev.Type == payments.SubscriptionStarted {
+					// Already subscribed: a repeated upgrade changes nothing.
+					break
+				}
+				// A renewal paid early extends the current period.
+				if open.EndsAt.After(start) {
+					start = open.EndsAt
+				}
+				if _, err := q.EndSubscriptionPeriod(ctx, database.EndSubscriptionPeriodParams{
+					EndReason: periodRenewed,
+					UserID:    ev.UserID,
+				}); err != nil {
+					return err
+				}
+			}
+			if _, err := q.OpenSubscriptionPeriod(ctx, database.OpenSubscriptionPeriodParams{
+				UserID:    ev.UserID,
+				StartedAt: start,
+				EndsAt:    periodEnd(start),
+			}); err != nil {
+				return err
+			}
+			upgraded = !hasOpen
+
+		case payments.PaymentFailed:
+			// The user keeps Chirpy Red until the paid period ends; if the
+			// provider can't collect by then, the expiry job takes it away.
+			n, err := q.SetSubscriptionPastDue(ctx, ev.UserID)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				status = webhookIgnored
+			}
+			return nil
+
+		case payments.SubscriptionCanceled, payments.SubscriptionExpired:
+			reason := periodDowngraded
+			if ev.Type == payments.SubscriptionExpired {
+				reason = periodExpired
+			}
+			if _, err := q.EndSubscriptionPeriod(ctx, database.EndSubscriptionPeriodParams{
+				EndReason: reason,
+				UserID:    ev.UserID,
+			}); err != nil {
+				return err
+			}
+			_, err := q.DowngradeFromChirpyRed(ctx, ev.UserID)
+			return err
+
+		default:
+			status = webhookIgnored
+			return nil
+		}
+
+		_, err = q.UpgradeToChirpyRed(ctx, ev.UserID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookIgnored, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if upgraded {
+		cfg.notify(ctx, ev.UserID, notificationChirpyRed, uuid.Nil, uuid.Nil)
+	}
+	return status, nil
+}
+
+// expireSubscriptions ends the periods that ran out without a renewal.
+func (cfg *apiConfig) expireSubscriptions(ctx context.Context) error {
+	expired, err := cfg.db.ExpireLapsedSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(expired) > 0 {
+		log.Printf("expired %d Chirpy Red subscriptions", len(expired))
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Suspension kinds. Suspended users can't log in or use their tokens, and
+// nobody but moderators sees their chirps; limited users keep using the API
+// but their chirps are only visible to themselves and moderators.
+const (
+	suspensionSuspended = "suspended"
+	suspensionLimited   = "limited"
+)
+
+type Suspension struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	EndsAt    time.Time  `json:"ends_at"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	ReportID  *uuid.UUID `json:"report_id"`
+	LiftedAt  *time.Time `json:"lifted_at"`
+	LiftedBy  *uuid.UUID `json:"lifted_by"`
+}
+
+func newSuspension(s database.Suspension) Suspension {
+	suspension := Suspension{
+		ID:        s.ID,
+		CreatedAt: s.CreatedAt,
+		UserID:    s.UserID,
+		Kind:      s.Kind,
+		Reason:    s.Reason,
+		EndsAt:    s.EndsAt,
+	}
+	if s.CreatedBy.Valid {
+		suspension.CreatedBy = &s.CreatedBy.UUID
+	}
+	if s.ReportID.Valid {
+		suspension.ReportID = &s.ReportID.UUID
+	}
+	if s.LiftedAt.Valid {
+		suspension.LiftedAt = &s.LiftedAt.Time
+	}
+	if s.LiftedBy.Valid {
+		suspension.LiftedBy = &s.LiftedBy.UUID
+	}
+	return suspension
+}
+
+// respondSuspended answers 403 with the reason and end of a suspension, so
+// that suspended users know why and until when.
+func respondSuspended(w http.ResponseWriter, s database.Suspension) {
+	type response struct {
+		Error  string    `json:"error"`
+		Reason string    `json:"reason"`
+		EndsAt time.Time `json:"ends_at"`
+	}
+	jsonResponse(w, http.StatusForbidden, response{
+		Error:  "account suspended",
+		Reason: s.Reason,
+		EndsAt: s.EndsAt,
+	})
+}
+
+// checkNotSuspended answers 403 and returns false when userID is
+// suspended. Limited users get through.
+func (cfg *apiConfig) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	suspension, err := cfg.db.GetActiveSuspension(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Couldn't check account status", err)
+		return false
+	}
+	if suspension.Kind != suspensionSuspended {
+		return true
+	}
+	respondSuspended(w, suspension)
+	return false
+}
+
+// isRestricted reports whether userID is suspended or limited. Their
+// chirps aren't shown to anyone but moderators, so they mustn't leave the
+// server either.
+func isRestricted(ctx context.Context, q *database.Queries, userID uuid.UUID) (bool, error) {
+	_, err := q.GetActiveSuspension(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// accountStatusHandler tells the caller whether they are suspended or
+// limited, why and until when. Suspended users can still use it with a
+// token issued before their suspension.
+func (cfg *apiConfig) accountStatusHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		// active, suspended or limited.
+		Status string     `json:"status"`
+		Reason string     `json:"reason,omitempty"`
+		EndsAt *time.Time `json:"ends_at,omitempty"`
+	}
+
+	userID, ok := cfg.authenticateToken(w, r)
+	if !ok {
+		return
+	}
+
+	suspension, err := cfg.db.GetActiveSuspension(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusOK, response{Status: "active"})
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "Couldn't check account status", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, response{
+		Status: suspension.Kind,
+		Reason: suspension.Reason,
+		EndsAt: &suspension.EndsAt,
+	})
+}
+
+// suspendUserHandler suspends or limits {userID} for a duration.
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		// Defaults to suspended.
+		Kind   string `json:"kind"`
+		Reason string `json:"reason"`
+		// e.g. "72h".
+		Duration string `json:"duration"`
+	}
+
+	userID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+	if params.Kind == "" {
+		params.Kind = suspensionSuspended
+	}
+	if params.Kind != suspensionSuspended && params.Kind != suspensionLimited {
+		jsonError(w, http.StatusBadRequest, "kind must be suspended or limited", nil)
+		return
+	}
+	if params.Reason == "" {
+		jsonError(w, http.StatusBadRequest, "a reason is required", nil)
+		return
+	}
+	duration, err := time.ParseDuration(params.Duration)
+	if err != nil || duration <= 0 {
+		jsonError(w, http.StatusBadRequest, "duration must be a positive duration (e.g. 72h)", err)
+		return
+	}
+
+	moderatorID := staffID(r)
+	if userID == moderatorID {
+		jsonError(w, http.StatusForbidden, "you can't suspend yourself", nil)
+		return
+	}
+
+	if _, err := cfg.db.GetUserById(r.Context(), userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to get user", err)
+		return
+	}
+
+	suspension, err := cfg.db.CreateSuspension(r.Context(), database.CreateSuspensionParams{
+		UserID:    userID,
+		Kind:      params.Kind,
+		Reason:    params.Reason,
+		EndsAt:    time.Now().UTC().Add(duration),
+		CreatedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to suspend user", err)
+		return
+	}
+
+	if suspension.Kind == suspensionSuspended {
+		cfg.notify(r.Context(), userID, notificationSuspended, uuid.Nil, uuid.Nil)
+	}
+
+	jsonResponse(w, http.StatusCreated, newSuspension(suspension))
+}
+
+// liftSuspensionsHandler ends the current suspensions and limits of
+// {userID} early.
+func (cfg *apiConfig) liftSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.LiftSuspensions(r.Context(), database.LiftSuspensionsParams{
+		LiftedBy: uuid.NullUUID{UUID: staffID(r), Valid: true},
+		UserID:   userID,
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to lift suspensions", err)
+		return
+	}
+	if n == 0 {
+		jsonError(w, http.StatusNotFound, "user is not suspended", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listSuspensionsHandler returns every suspension and limit of {userID},
+// newest first, including those that ended.
+func (cfg *apiConfig) listSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUUIDPathValue(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	dbSuspensions, err := cfg.db.GetSuspensions(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list suspensions", err)
+		return
+	}
+
+	suspensions := make([]Suspension, 0, len(dbSuspensions))
+	for _, s := range dbSuspensions {
+		suspensions = append(suspensions, newSuspension(s))
+	}
+
+	jsonResponse(w, http.StatusOK, suspensions)
+}
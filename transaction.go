@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+// withTx runs fn inside a database transaction, committing when it returns
+// nil and rolling back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -11,14 +11,41 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Password     string    `json:"-"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Email         string     `json:"email"`
+	Password      string     `json:"-"`
+	Token         string     `json:"token"`
+	RefreshToken  string     `json:"refresh_token"`
+	IsChirpyRed   bool       `json:"is_chirpy_red"`
+	IsPrivate     bool       `json:"is_private"`
+	PinnedChirpID *uuid.UUID `json:"pinned_chirp_id"`
+	Role          string     `json:"role"`
+
+	CollapseSensitive bool `json:"collapse_sensitive"`
+}
+
+func isChirpyRed(user database.User) bool {
+	return user.IsChirpyRed.Valid && user.IsChirpyRed.Bool
+}
+
+func newUser(user database.User) User {
+	u := User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: isChirpyRed(user),
+		IsPrivate:   user.IsPrivate,
+		Role:        user.Role,
+
+		CollapseSensitive: user.CollapseSensitive,
+	}
+	if user.PinnedChirpID.Valid {
+		u.PinnedChirpID = &user.PinnedChirpID.UUID
+	}
+	return u
 }
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -55,19 +82,8 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	IsChirpyRed := false
-	if user.IsChirpyRed.Valid {
-		IsChirpyRed = user.IsChirpyRed.Bool
-	}
-
 	jsonResponse(w, http.StatusCreated, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: IsChirpyRed,
-		},
+		User: newUser(user),
 	})
 }
 
@@ -91,6 +107,9 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
 		return
 	}
+	if !cfg.checkUserExists(w, r, userID) || !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
 
 	var p Params
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
@@ -109,6 +128,12 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	oldUser, err := cfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "couldn't get user", err)
+		return
+	}
+
 	user, err := cfg.db.UpdateUserByID(r.Context(), database.UpdateUserByIDParams{
 		ID:             userID,
 		Email:          p.Email,
@@ -119,18 +144,46 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	IsChirpyRed := false
-	if user.IsChirpyRed.Valid {
-		IsChirpyRed = user.IsChirpyRed.Bool
+	if user.Email != oldUser.Email {
+		cfg.audit(r, auditEvent{
+			Action:   auditEmailChanged,
+			ActorID:  userID,
+			TargetID: userID,
+			Metadata: map[string]string{"old_email": oldUser.Email, "new_email": user.Email},
+		})
 	}
+	// The password is set on every update.
+	cfg.audit(r, auditEvent{Action: auditPasswordChanged, ActorID: userID, TargetID: userID})
 
 	jsonResponse(w, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: IsChirpyRed,
-		},
+		User: newUser(user),
+	})
+}
+
+func (cfg *apiConfig) updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		CollapseSensitive bool `json:"collapse_sensitive"`
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return
+	}
+
+	user, err := cfg.db.SetCollapseSensitive(r.Context(), database.SetCollapseSensitiveParams{
+		ID:                userID,
+		CollapseSensitive: params.CollapseSensitive,
 	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "couldn't update settings", err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newUser(user))
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpViewer describes who is reading chirps so that every read handler
+// applies the same filtering rules.
+type chirpViewer struct {
+	userID    uuid.UUID // uuid.Nil for anonymous requests
+	muted     map[uuid.UUID]bool
+	following map[uuid.UUID]bool
+	authors   map[uuid.UUID]database.User
+	// Authors currently suspended or limited.
+	restricted map[uuid.UUID]bool
+
+	// Anonymous readers get sensitive chirps collapsed.
+	collapseSensitive bool
+
+	// Moderators can still read chirps they hid.
+	moderator bool
+}
+
+func (cfg *apiConfig) loadChirpViewer(ctx context.Context, userID uuid.UUID) (*chirpViewer, error) {
+	viewer := &chirpViewer{
+		userID:    userID,
+		muted:     map[uuid.UUID]bool{},
+		following: map[uuid.UUID]bool{},
+		authors:   map[uuid.UUID]database.User{},
+
+		restricted: map[uuid.UUID]bool{},
+
+		collapseSensitive: true,
+	}
+	if userID == uuid.Nil {
+		return viewer, nil
+	}
+
+	user, err := cfg.db.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	viewer.authors[user.ID] = user
+	viewer.collapseSensitive = user.CollapseSensitive
+	viewer.moderator = hasRole(user.Role, roleModerator)
+
+	mutedIDs, err := cfg.db.GetMutedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range mutedIDs {
+		viewer.muted[id] = true
+	}
+
+	followeeIDs, err := cfg.db.GetFolloweeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range followeeIDs {
+		viewer.following[id] = true
+	}
+
+	return viewer, nil
+}
+
+// loadAuthors fetches the authors of chirps that aren't cached yet, in a
+// single query.
+func (cfg *apiConfig) loadAuthors(ctx context.Context, viewer *chirpViewer, chirps []database.Chirp) error {
+	missing := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, c := range chirps {
+		if _, ok := viewer.authors[c.UserID]; ok || seen[c.UserID] {
+			continue
+		}
+		seen[c.UserID] = true
+		missing = append(missing, c.UserID)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	users, err := cfg.db.GetUsersByIDs(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		viewer.authors[u.ID] = u
+	}
+
+	restrictedIDs, err := cfg.db.GetRestrictedUserIDs(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, id := range restrictedIDs {
+		viewer.restricted[id] = true
+	}
+	return nil
+}
+
+// canSeeAuthor reports whether the viewer may read chirps written by
+// authorID. Private accounts are only readable by themselves and their
+// approved followers, and suspended or limited accounts by themselves and
+// moderators.
+func (v *chirpViewer) canSeeAuthor(authorID uuid.UUID) bool {
+	if authorID == v.userID {
+		return true
+	}
+	author, ok := v.authors[authorID]
+	if !ok {
+		return false
+	}
+	if v.restricted[authorID] && !v.moderator {
+		return false
+	}
+	if author.IsPrivate && !v.following[authorID] {
+		return false
+	}
+	return true
+}
+
+// canSeeChirp applies the per-chirp rules on top of canSeeAuthor.
+// Deleted chirps are hidden from everyone, even while they can still be
+// restored, scheduled chirps are only visible to their author, and chirps
+// hidden by a moderator to their author and moderators.
+func (v *chirpViewer) canSeeChirp(chirp database.Chirp) bool {
+	if chirp.DeletedAt.Valid {
+		return false
+	}
+	if chirp.PublishAt.Valid && chirp.UserID != v.userID {
+		return false
+	}
+	if chirp.HiddenAt.Valid && chirp.UserID != v.userID && !v.moderator {
+		return false
+	}
+	return v.canSeeAuthor(chirp.UserID)
+}
+
+// canSee reports whether a single chirp is visible to the viewer. Hidden
+// chirps must be answered with a 404, never a 403.
+func (cfg *apiConfig) canSee(ctx context.Context, viewer *chirpViewer, chirp database.Chirp) (bool, error) {
+	if err := cfg.loadAuthors(ctx, viewer, []database.Chirp{chirp}); err != nil {
+		return false, err
+	}
+	return viewer.canSeeChirp(chirp), nil
+}
+
+// getVisibleChirp loads a chirp and reports sql.ErrNoRows when it either
+// doesn't exist or is hidden from the viewer.
+func (cfg *apiConfig) getVisibleChirp(ctx context.Context, viewer *chirpViewer, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	visible, err := cfg.canSee(ctx, viewer, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !visible {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// filterVisible drops chirps the viewer isn't allowed to see at all.
+func (cfg *apiConfig) filterVisible(ctx context.Context, viewer *chirpViewer, chirps []database.Chirp) ([]database.Chirp, error) {
+	if err := cfg.loadAuthors(ctx, viewer, chirps); err != nil {
+		return nil, err
+	}
+
+	out := make([]database.Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		if !viewer.canSeeChirp(c) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// filterListing additionally drops chirps the viewer chose not to see in
+// listings (GetChirps and timelines), such as those of muted users.
+func (cfg *apiConfig) filterListing(ctx context.Context, viewer *chirpViewer, chirps []database.Chirp) ([]database.Chirp, error) {
+	visible, err := cfg.filterVisible(ctx, viewer, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]database.Chirp, 0, len(visible))
+	for _, c := range visible {
+		if viewer.muted[c.UserID] {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var deliveryStatuses = map[string]bool{
+	deliveryPending:   true,
+	deliveryDelivered: true,
+	deliveryDead:      true,
+}
+
+const (
+	defaultDeliveriesPage = 50
+	maxDeliveriesPage     = 200
+)
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	// Only returned when the endpoint is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookEndpoint(e database.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		URL:       e.Url,
+		Events:    e.Events,
+	}
+}
+
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  int32     `json:"status_code"`
+	Error       string    `json:"error"`
+	DurationMs  int32     `json:"duration_ms"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID        `json:"id"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Event         string           `json:"event"`
+	Payload       json.RawMessage  `json:"payload"`
+	Status        string           `json:"status"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at"`
+	Attempts      []WebhookAttempt `json:"attempts"`
+}
+
+// webhookOwner tells whose endpoints a request manages: the caller's on
+// /api routes, the admin ones (no user) on /admin routes.
+func (cfg *apiConfig) webhookOwner(w http.ResponseWriter, r *http.Request, admin bool) (uuid.NullUUID, bool) {
+	if admin {
+		// Admin routes are behind requireRole.
+		return uuid.NullUUID{}, true
+	}
+
+	userID, ok := cfg.authenticateUser(w, r)
+	if !ok {
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}, true
+}
+
+// ownedWebhookEndpoint loads the {endpointID} endpoint, answering 404 when
+// it belongs to someone else.
+func (cfg *apiConfig) ownedWebhookEndpoint(w http.ResponseWriter, r *http.Request, admin bool) (database.WebhookEndpoint, bool) {
+	owner, ok := cfg.webhookOwner(w, r, admin)
+	if !ok {
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpointID, ok := parseUUIDPathValue(w, r, "endpointID")
+	if !ok {
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := cfg.db.GetWebhookEndpoint(r.Context(), database.GetWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: owner,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonError(w, http.StatusNotFound, "webhook endpoint not found", err)
+			return database.WebhookEndpoint{}, false
+		}
+		jsonError(w, http.StatusInternalServerError, "failed to load webhook endpoint", err)
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+func (cfg *apiConfig) createWebhookEndpointHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type parameters struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+
+		owner, ok := cfg.webhookOwner(w, r, admin)
+		if !ok {
+			return
+		}
+
+		var params parameters
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+			return
+		}
+
+		u, err := url.Parse(params.URL)
+		if err == nil {
+			err = cfg.webhookClient.Policy.CheckURL(u)
+		}
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "url must be an https URL on a public address", err)
+			return
+		}
+		if len(params.Events) == 0 {
+			jsonError(w, http.StatusBadRequest, "at least one event is required", nil)
+			return
+		}
+		for _, event := range params.Events {
+			if !webhookEventTypes[event] {
+				jsonError(w, http.StatusBadRequest, "unknown event "+strconv.Quote(event), nil)
+				return
+			}
+		}
+
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to generate secret", err)
+			return
+		}
+		secret := "whsec_" + hex.EncodeToString(key)
+
+		endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+			UserID: owner,
+			Url:    u.String(),
+			Secret: secret,
+			Events: params.Events,
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to create webhook endpoint", err)
+			return
+		}
+
+		resp := newWebhookEndpoint(endpoint)
+		resp.Secret = endpoint.Secret
+		jsonResponse(w, http.StatusCreated, resp)
+	}
+}
+
+func (cfg *apiConfig) listWebhookEndpointsHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, ok := cfg.webhookOwner(w, r, admin)
+		if !ok {
+			return
+		}
+
+		dbEndpoints, err := cfg.db.GetWebhookEndpoints(r.Context(), owner)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to list webhook endpoints", err)
+			return
+		}
+
+		endpoints := make([]WebhookEndpoint, 0, len(dbEndpoints))
+		for _, e := range dbEndpoints {
+			endpoints = append(endpoints, newWebhookEndpoint(e))
+		}
+		jsonResponse(w, http.StatusOK, endpoints)
+	}
+}
+
+func (cfg *apiConfig) deleteWebhookEndpointHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint, ok := cfg.ownedWebhookEndpoint(w, r, admin)
+		if !ok {
+			return
+		}
+
+		if _, err := cfg.db.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+			ID:     endpoint.ID,
+			UserID: endpoint.UserID,
+		}); err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to delete webhook endpoint", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listWebhookDeliveriesHandler is the delivery log of an endpoint: its
+// latest deliveries, newest first, with every attempt made.
+func (cfg *apiConfig) listWebhookDeliveriesHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint, ok := cfg.ownedWebhookEndpoint(w, r, admin)
+		if !ok {
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		if status != "" && !deliveryStatuses[status] {
+			jsonError(w, http.StatusBadRequest, "unknown status", nil)
+			return
+		}
+
+		limit := defaultDeliveriesPage
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxDeliveriesPage {
+				jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxDeliveriesPage), err)
+				return
+			}
+			limit = n
+		}
+
+		dbDeliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+			EndpointID: endpoint.ID,
+			Status:     status,
+			MaxResults: int32(limit),
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to list deliveries", err)
+			return
+		}
+
+		ids := make([]uuid.UUID, 0, len(dbDeliveries))
+		for _, d := range dbDeliveries {
+			ids = append(ids, d.ID)
+		}
+		dbAttempts, err := cfg.db.GetWebhookDeliveryAttempts(r.Context(), ids)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to list attempts", err)
+			return
+		}
+		attempts := map[uuid.UUID][]WebhookAttempt{}
+		for _, a := range dbAttempts {
+			attempts[a.DeliveryID] = append(attempts[a.DeliveryID], WebhookAttempt{
+				AttemptedAt: a.AttemptedAt,
+				StatusCode:  a.StatusCode,
+				Error:       a.Error,
+				DurationMs:  a.DurationMs,
+			})
+		}
+
+		deliveries := make([]WebhookDelivery, 0, len(dbDeliveries))
+		for _, d := range dbDeliveries {
+			delivery := WebhookDelivery{
+				ID:        d.ID,
+				CreatedAt: d.CreatedAt,
+				Event:     d.Event,
+				Payload:   json.RawMessage(d.Payload),
+				Status:    d.Status,
+				Attempts:  attempts[d.ID],
+			}
+			if d.Status == deliveryPending {
+				delivery.NextAttemptAt = &d.NextAttemptAt
+			}
+			if delivery.Attempts == nil {
+				delivery.Attempts = []WebhookAttempt{}
+			}
+			deliveries = append(deliveries, delivery)
+		}
+
+		jsonResponse(w, http.StatusOK, deliveries)
+	}
+}
+
+// testWebhookEndpointHandler sends a ping event right away and reports how
+// the endpoint answered. Test deliveries are logged but never retried.
+func (cfg *apiConfig) testWebhookEndpointHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type response struct {
+			DeliveryID uuid.UUID `json:"delivery_id"`
+			Delivered  bool      `json:"delivered"`
+			Error      string    `json:"error,omitempty"`
+		}
+
+		endpoint, ok := cfg.ownedWebhookEndpoint(w, r, admin)
+		if !ok {
+			return
+		}
+
+		payload, err := newOutboundEvent(webhookPing, struct {
+			EndpointID uuid.UUID `json:"endpoint_id"`
+		}{endpoint.ID})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to build event", err)
+			return
+		}
+
+		delivery, err := cfg.db.CreateWebhookDelivery(r.Context(), database.CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			Event:      webhookPing,
+			Payload:    string(payload),
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to create delivery", err)
+			return
+		}
+
+		resp := response{DeliveryID: delivery.ID}
+		sendErr := cfg.sendWebhook(r.Context(), delivery.ID, endpoint.Url, endpoint.Secret, webhookPing, string(payload))
+		if sendErr == nil {
+			resp.Delivered = true
+			err = cfg.db.MarkWebhookDelivered(r.Context(), delivery.ID)
+		} else {
+			resp.Error = webhookErrorMessage(sendErr)
+			err = cfg.db.KillWebhookDelivery(r.Context(), delivery.ID)
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to update delivery", err)
+			return
+		}
+
+		jsonResponse(w, http.StatusOK, resp)
+	}
+}
+
+// retryWebhookDeliveryHandler puts a dead delivery back in the queue.
+func (cfg *apiConfig) retryWebhookDeliveryHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint, ok := cfg.ownedWebhookEndpoint(w, r, admin)
+		if !ok {
+			return
+		}
+
+		deliveryID, ok := parseUUIDPathValue(w, r, "deliveryID")
+		if !ok {
+			return
+		}
+
+		n, err := cfg.db.RequeueWebhookDelivery(r.Context(), database.RequeueWebhookDeliveryParams{
+			ID:         deliveryID,
+			EndpointID: endpoint.ID,
+		})
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to retry delivery", err)
+			return
+		}
+		if n == 0 {
+			jsonError(w, http.StatusNotFound, "dead delivery not found", nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
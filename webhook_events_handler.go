@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/database"
+)
+
+var webhookStatuses = map[string]bool{
+	webhookReceived:  true,
+	webhookProcessed: true,
+	webhookIgnored:   true,
+	webhookFailed:    true,
+}
+
+const (
+	defaultWebhookEventsPage = 50
+	maxWebhookEventsPage     = 200
+)
+
+type WebhookEvent struct {
+	ID          string     `json:"id"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	Event       string     `json:"event"`
+	Payload     string     `json:"payload"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+	Attempts    int32      `json:"attempts"`
+	ProcessedAt *time.Time `json:"processed_at"`
+}
+
+func newWebhookEvent(e database.WebhookEvent) WebhookEvent {
+	event := WebhookEvent{
+		ID:         e.ID,
+		ReceivedAt: e.ReceivedAt,
+		Event:      e.Event,
+		Payload:    e.Payload,
+		Status:     e.Status,
+		Error:      e.Error,
+		Attempts:   e.Attempts,
+	}
+	if e.ProcessedAt.Valid {
+		event.ProcessedAt = &e.ProcessedAt.Time
+	}
+	return event
+}
+
+// listWebhookEventsHandler returns received webhooks, newest first,
+// optionally filtered by status. The next page is requested with
+// before=next_before.
+func (cfg *apiConfig) listWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Events     []WebhookEvent `json:"events"`
+		NextBefore *string        `json:"next_before"`
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !webhookStatuses[status] {
+		jsonError(w, http.StatusBadRequest, "unknown status", nil)
+		return
+	}
+
+	limit := defaultWebhookEventsPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxWebhookEventsPage {
+			jsonError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxWebhookEventsPage), err)
+			return
+		}
+		limit = n
+	}
+
+	dbEvents, err := cfg.db.GetWebhookEvents(r.Context(), database.GetWebhookEventsParams{
+		Status:     status,
+		Before:     r.URL.Query().Get("before"),
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to list webhook events", err)
+		return
+	}
+
+	resp := response{Events: make([]WebhookEvent, 0, len(dbEvents))}
+	for _, e := range dbEvents {
+		resp.Events = append(resp.Events, newWebhookEvent(e))
+	}
+	if len(dbEvents) == limit {
+		last := dbEvents[len(dbEvents)-1].ID
+		resp.NextBefore = &last
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// replayWebhookEventHandler processes a failed event again.
+func (cfg *apiConfig) replayWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("eventID")
+
+	event, err := cfg.db.ReplayWebhookEvent(r.Context(), eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := cfg.db.GetWebhookEvent(r.Context(), eventID); err != nil {
+			jsonError(w, http.StatusNotFound, "webhook event not found", err)
+			return
+		}
+		jsonError(w, http.StatusConflict, "only failed events can be replayed", nil)
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to replay webhook event", err)
+		return
+	}
+
+	// The outcome is recorded on the event either way.
+	procErr := cfg.processWebhookEvent(r.Context(), event)
+
+	event, err = cfg.db.GetWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to get webhook event", err)
+		return
+	}
+	if procErr != nil && event.Status != webhookFailed {
+		jsonError(w, http.StatusInternalServerError, "failed to record the outcome", procErr)
+		return
+	}
+	cfg.auditUpgrade(r, staffID(r), event)
+
+	jsonResponse(w, http.StatusOK, newWebhookEvent(event))
+}
@@ -1,63 +1,140 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
-	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/payments"
 	"github.com/google/uuid"
 )
 
-type Event struct {
-	Event string `json:"event"`
-	Data  struct {
-		UserID uuid.UUID `json:"user_id"`
-	} `json:"data"`
-}
+// Webhook event statuses.
+const (
+	webhookReceived  = "received"
+	webhookProcessed = "processed"
+	webhookIgnored   = "ignored"
+	webhookFailed    = "failed"
+)
 
-func (cfg *apiConfig) webhooks(w http.ResponseWriter, r *http.Request) {
+const maxWebhookBodySize = 1 << 16
 
-	apiKey, err := auth.GetAPIKey(r.Header)
-	if err != nil {
-		jsonError(w, http.StatusUnauthorized, "", err)
-		return
+// An event still received after this long was abandoned by the request
+// processing it, and is processed again when the provider redelivers it.
+const webhookEventClaimTimeout = 5 * time.Minute
+
+// paymentWebhookHandler receives the webhooks of a payment provider.
+func (cfg *apiConfig) paymentWebhookHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := cfg.paymentProviders[name]
+		if !ok {
+			jsonError(w, http.StatusNotFound, "payment provider not enabled", nil)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "couldn't read request body", err)
+			return
+		}
+		if len(body) > maxWebhookBodySize {
+			jsonError(w, http.StatusRequestEntityTooLarge, "request body too large", nil)
+			return
+		}
+
+		if _, ok := cfg.receivePaymentWebhook(w, r, provider, body); !ok {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 	}
+}
 
-	if apiKey != cfg.PolkaKey {
+// receivePaymentWebhook authenticates a webhook, records its event and
+// processes it, unless it was already. It returns the recorded event.
+func (cfg *apiConfig) receivePaymentWebhook(w http.ResponseWriter, r *http.Request, provider payments.Provider, body []byte) (database.WebhookEvent, bool) {
+	if err := provider.Authenticate(r, body); err != nil {
 		jsonError(w, http.StatusUnauthorized, "", err)
-		return
+		return database.WebhookEvent{}, false
+	}
+
+	ev, err := provider.DecodeEvent(body)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
+		return database.WebhookEvent{}, false
 	}
 
-	var ev struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
+	event, err := cfg.db.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+		ID:           ev.ID,
+		Event:        ev.Name,
+		Payload:      string(body),
+		Provider:     provider.Name(),
+		StaleSeconds: int64(webhookEventClaimTimeout.Seconds()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already processed, or being processed by another request.
+		event, err = cfg.db.GetWebhookEvent(r.Context(), ev.ID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to get event", err)
+			return database.WebhookEvent{}, false
+		}
+		return event, true
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to record event", err)
+		return database.WebhookEvent{}, false
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
-		jsonError(w, http.StatusBadRequest, "couldn't decode request body", err)
-		return
+	// Providers retry until they get a 2xx, so failures answer 500.
+	if err := cfg.processWebhookEvent(r.Context(), event); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to process event", err)
+		return database.WebhookEvent{}, false
 	}
 
-	if ev.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
+	event, err = cfg.db.GetWebhookEvent(r.Context(), ev.ID)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to get event", err)
+		return database.WebhookEvent{}, false
 	}
+	cfg.auditUpgrade(r, uuid.Nil, event)
+	return event, true
+}
 
-	fmt.Printf("UserID: %s", ev.Data.UserID)
+// processWebhookEvent applies a recorded event and stores the outcome.
+func (cfg *apiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) error {
+	status, procErr := cfg.applyWebhookEvent(ctx, event)
+	if procErr != nil {
+		status = webhookFailed
+		log.Printf("webhook event %s (%s) failed: %v", event.ID, event.Event, procErr)
+	}
 
-	if _, err := cfg.db.UpgradeToChirpyRed(r.Context(), ev.Data.UserID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		jsonError(w, http.StatusInternalServerError, "failed to upgrade user", err)
-		return
+	errMsg := ""
+	if procErr != nil {
+		errMsg = procErr.Error()
+	}
+	if err := cfg.db.SetWebhookEventResult(ctx, database.SetWebhookEventResultParams{
+		Status: status,
+		Error:  errMsg,
+		ID:     event.ID,
+	}); err != nil {
+		return err
 	}
+	return procErr
+}
 
-	w.WriteHeader(http.StatusNoContent)
+func (cfg *apiConfig) applyWebhookEvent(ctx context.Context, event database.WebhookEvent) (string, error) {
+	provider, ok := cfg.paymentProviders[event.Provider]
+	if !ok {
+		return "", fmt.Errorf("payment provider %q is not enabled", event.Provider)
+	}
+	ev, err := provider.DecodeEvent([]byte(event.Payload))
+	if err != nil {
+		return "", err
+	}
+	return cfg.applySubscriptionEvent(ctx, ev)
 }
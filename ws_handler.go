@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AymaneIsmail/chirpy/internal/auth"
+	"github.com/AymaneIsmail/chirpy/internal/database"
+	"github.com/AymaneIsmail/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	wsPingInterval     = 30 * time.Second
+	wsPongTimeout      = 2 * wsPingInterval
+	wsWriteTimeout     = 10 * time.Second
+	wsSendBuffer       = 64
+	wsMaxMessageSize   = 4 << 10
+	wsMaxSubscriptions = 20
+
+	// Clients are warned this long before their token expires, so that
+	// they can send a fresh one instead of being disconnected.
+	wsExpiryWarning = time.Minute
+)
+
+const (
+	wsChannelHome          = "home"
+	wsChannelNotifications = "notifications"
+	wsChannelThread        = "thread"
+)
+
+// wsClientMessage is what clients send: subscribe and unsubscribe to
+// channels, or auth to replace their token before it expires.
+type wsClientMessage struct {
+	Type    string    `json:"type"`
+	Channel string    `json:"channel"`
+	ChirpID uuid.UUID `json:"chirp_id"`
+	Token   string    `json:"token"`
+}
+
+type wsEvent struct {
+	Type      string     `json:"type"`
+	Channel   string     `json:"channel,omitempty"`
+	EventID   int64      `json:"event_id,omitempty"`
+	Chirp     *Chirp     `json:"chirp,omitempty"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Message   string     `json:"message,omitempty"`
+
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+
+	// Replies from the read loop, written by the write loop.
+	replies chan wsEvent
+	// Set by the read loop, read by the write loop.
+	tokenRefreshed chan time.Time
+
+	mu            sync.Mutex
+	home          bool
+	notifications bool
+	threads       map[uuid.UUID]bool
+}
+
+// wsHandler upgrades to a WebSocket on which clients subscribe to their
+// home timeline, their notifications or a chirp's thread. The access token comes from the
+// Authorization header or, for browsers, the access_token query parameter.
+func (cfg *apiConfig) wsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		jsonError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.JWTSecret)
+	if err != nil {
+		jsonError(w, http.StatusUnauthorized, "invalid or expired token", err)
+		return
+	}
+	if !cfg.checkUserExists(w, r, claims.UserID) || !cfg.checkNotSuspended(w, r, claims.UserID) {
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	conn.MaxMessageSize = wsMaxMessageSize
+
+	session := &wsSession{
+		cfg:            cfg,
+		conn:           conn,
+		userID:         claims.UserID,
+		replies:        make(chan wsEvent, wsSendBuffer),
+		tokenRefreshed: make(chan time.Time, 1),
+		threads:        map[uuid.UUID]bool{},
+	}
+
+	// The request context is not tied to a hijacked connection.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go session.writeLoop(ctx, cancel, claims.ExpiresAt)
+	session.readLoop(ctx, cancel)
+}
+
+func (s *wsSession) readLoop(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+
+	s.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	s.conn.PongHandler = func([]byte) {
+		s.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	}
+
+	for {
+		opcode, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				s.conn.Close(websocket.CloseGoingAway, "")
+			}
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+
+		if opcode != websocket.OpText {
+			s.conn.Close(websocket.CloseUnsupportedData, "text messages only")
+			return
+		}
+
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.reply(wsEvent{Type: "error", Message: "invalid JSON message"})
+			continue
+		}
+		s.handle(ctx, msg)
+	}
+}
+
+func (s *wsSession) handle(ctx context.Context, msg wsClientMessage) {
+	switch msg.Type {
+	case "subscribe":
+		s.subscribe(ctx, msg)
+	case "unsubscribe":
+		s.mu.Lock()
+		switch msg.Channel {
+		case wsChannelHome:
+			s.home = false
+		case wsChannelNotifications:
+			s.notifications = false
+		case wsChannelThread:
+			delete(s.threads, msg.ChirpID)
+		}
+		s.mu.Unlock()
+		s.reply(wsEvent{Type: "unsubscribed", Channel: msg.Channel, ChirpID: threadID(msg)})
+	case "auth":
+		claims, err := auth.ParseJWT(msg.Token, s.cfg.JWTSecret)
+		if err != nil || claims.UserID != s.userID {
+			s.reply(wsEvent{Type: "error", Message: "invalid token"})
+			return
+		}
+		// The session then ends with the token it already had.
+		suspension, err := s.cfg.db.GetActiveSuspension(ctx, s.userID)
+		if err == nil && suspension.Kind == suspensionSuspended {
+			s.reply(wsEvent{Type: "error", Message: "account suspended"})
+			return
+		}
+		select {
+		case <-s.tokenRefreshed:
+		default:
+		}
+		s.tokenRefreshed <- claims.ExpiresAt
+		s.reply(wsEvent{Type: "authenticated", ExpiresAt: &claims.ExpiresAt})
+	default:
+		s.reply(wsEvent{Type: "error", Message: "unknown message type"})
+	}
+}
+
+func (s *wsSession) subscribe(ctx context.Context, msg wsClientMessage) {
+	switch msg.Channel {
+	case wsChannelHome:
+		s.mu.Lock()
+		s.home = true
+		s.mu.Unlock()
+
+	case wsChannelThread:
+		viewer, err := s.cfg.loadChirpViewer(ctx, s.userID)
+		if err != nil {
+			s.reply(wsEvent{Type: "error", Message: "Cannot load viewer"})
+			return
+		}
+		if _, err := s.cfg.getVisibleChirp(ctx, viewer, msg.ChirpID); err != nil {
+			s.reply(wsEvent{Type: "error", Channel: msg.Channel, Message: "chirp not found"})
+			return
+		}
+		s.mu.Lock()
+		full := len(s.threads) >= wsMaxSubscriptions
+		if !full {
+			s.threads[msg.ChirpID] = true
+		}
+		s.mu.Unlock()
+		if full {
+			s.reply(wsEvent{Type: "error", Channel: msg.Channel, Message: "too many subscriptions"})
+			return
+		}
+
+	case wsChannelNotifications:
+		s.mu.Lock()
+		s.notifications = true
+		s.mu.Unlock()
+
+	default:
+		s.reply(wsEvent{Type: "error", Channel: msg.Channel, Message: "unknown channel"})
+		return
+	}
+
+	s.reply(wsEvent{Type: "subscribed", Channel: msg.Channel, ChirpID: threadID(msg)})
+}
+
+func threadID(msg wsClientMessage) *uuid.UUID {
+	if msg.Channel != wsChannelThread {
+		return nil
+	}
+	return &msg.ChirpID
+}
+
+// reply queues an event for the write loop. A client that lets its queue
+// fill up is disconnected rather than buffered without bounds.
+func (s *wsSession) reply(event wsEvent) {
+	select {
+	case s.replies <- event:
+	default:
+		s.conn.Close(websocket.CloseTryAgainLater, "too many pending messages")
+	}
+}
+
+func (s *wsSession) writeLoop(ctx context.Context, cancel context.CancelFunc, expiresAt time.Time) {
+	defer cancel()
+
+	events := s.cfg.chirpHub.subscribe()
+	defer s.cfg.chirpHub.unsubscribe(events)
+	notifications := s.cfg.notificationHub.subscribe(s.userID)
+	defer s.cfg.notificationHub.unsubscribe(s.userID, notifications)
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	warned := false
+	expiry := time.NewTimer(time.Until(expiresAt.Add(-wsExpiryWarning)))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.OpPing, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				s.conn.Close(websocket.CloseGoingAway, "")
+				return
+			}
+
+		case expiresAt = <-s.tokenRefreshed:
+			warned = false
+			expiry.Reset(time.Until(expiresAt.Add(-wsExpiryWarning)))
+
+		case <-expiry.C:
+			if warned {
+				s.conn.Close(websocket.ClosePolicyViolation, "token expired")
+				return
+			}
+			warned = true
+			if !s.write(wsEvent{Type: "token.expiring", ExpiresAt: &expiresAt}) {
+				return
+			}
+			expiry.Reset(time.Until(expiresAt))
+
+		case event := <-s.replies:
+			if !s.write(event) {
+				return
+			}
+
+		case id, ok := <-notifications:
+			if !ok {
+				s.conn.Close(websocket.CloseTryAgainLater, "too slow to keep up")
+				return
+			}
+			event, err := s.notification(ctx, id)
+			if err != nil {
+				log.Printf("websocket: %v", err)
+				s.conn.Close(websocket.CloseInternalError, "")
+				return
+			}
+			if event != nil && !s.write(*event) {
+				return
+			}
+
+		case event, ok := <-events:
+			if !ok {
+				s.conn.Close(websocket.CloseTryAgainLater, "too slow to keep up")
+				return
+			}
+			out, err := s.route(ctx, event)
+			if err != nil {
+				log.Printf("websocket: %v", err)
+				s.conn.Close(websocket.CloseInternalError, "")
+				return
+			}
+			for _, e := range out {
+				if !s.write(e) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *wsSession) write(event wsEvent) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("websocket: %v", err)
+		return true
+	}
+	if err := s.conn.WriteMessage(websocket.OpText, data, time.Now().Add(wsWriteTimeout)); err != nil {
+		s.conn.Close(websocket.CloseGoingAway, "")
+		return false
+	}
+	return true
+}
+
+// notification loads a new notification for the notifications channel. It
+// returns nil when the session isn't subscribed or the notification is
+// already gone.
+func (s *wsSession) notification(ctx context.Context, id uuid.UUID) (*wsEvent, error) {
+	s.mu.Lock()
+	subscribed := s.notifications
+	s.mu.Unlock()
+	if !subscribed {
+		return nil, nil
+	}
+
+	n, err := s.cfg.db.GetNotification(ctx, database.GetNotificationParams{
+		ID:     id,
+		UserID: s.userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	notification := newNotification(n)
+	return &wsEvent{Type: "notification", Channel: wsChannelNotifications, Notification: &notification}, nil
+}
+
+// route turns a chirp event into one message per subscribed channel it
+// belongs to. The viewer is reloaded every time so that follows, mutes and
+// privacy changes apply to a long-lived connection.
+func (s *wsSession) route(ctx context.Context, event database.ChirpEvent) ([]wsEvent, error) {
+	s.mu.Lock()
+	home := s.home
+	threads := make(map[uuid.UUID]bool, len(s.threads))
+	for id := range s.threads {
+		threads[id] = true
+	}
+	s.mu.Unlock()
+
+	if !home && len(threads) == 0 {
+		return nil, nil
+	}
+
+	viewer, err := s.cfg.loadChirpViewer(ctx, s.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	chirp, err := s.cfg.db.GetChirp(ctx, event.ChirpID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	found := err == nil
+
+	channels := []string{}
+	if home && !viewer.muted[event.UserID] && (event.UserID == s.userID || viewer.following[event.UserID]) {
+		channels = append(channels, wsChannelHome)
+	}
+	if threads[event.ChirpID] || (found && chirp.QuoteOfID.Valid && threads[chirp.QuoteOfID.UUID]) {
+		channels = append(channels, wsChannelThread)
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	out := make([]wsEvent, 0, len(channels))
+	switch event.Kind {
+	case "created":
+		if !found {
+			return nil, nil
+		}
+		visible, err := s.cfg.canSee(ctx, viewer, chirp)
+		if err != nil || !visible {
+			return nil, err
+		}
+		chirps, err := s.cfg.hydrateChirps(ctx, viewer, []database.Chirp{chirp})
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			out = append(out, wsEvent{Type: "chirp.created", Channel: channel, EventID: event.ID, Chirp: &chirps[0]})
+		}
+
+	case "deleted":
+		if err := s.cfg.loadAuthors(ctx, viewer, []database.Chirp{{UserID: event.UserID}}); err != nil {
+			return nil, err
+		}
+		if !viewer.canSeeAuthor(event.UserID) {
+			return nil, nil
+		}
+		for _, channel := range channels {
+			out = append(out, wsEvent{Type: "chirp.deleted", Channel: channel, EventID: event.ID, ChirpID: &event.ChirpID})
+		}
+	}
+	return out, nil
+}